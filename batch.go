@@ -0,0 +1,328 @@
+package i18n
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// BatchStorage is implemented by storages that can write many translations in
+// one operation. I18n falls back to writing translations one at a time for
+// storages that do not implement it.
+type BatchStorage interface {
+	Storage
+
+	// StoreMany adds or updates every translation
+	StoreMany([]*Translation) error
+
+	// DeleteMany removes every translation
+	DeleteMany([]*Translation) error
+
+	// ReplaceAll replaces the stored translations with the ones given
+	ReplaceAll([]*Translation) error
+}
+
+// ContextBatchStorage is a BatchStorage whose operations accept a context
+type ContextBatchStorage interface {
+	BatchStorage
+
+	StoreManyContext(context.Context, []*Translation) error
+	DeleteManyContext(context.Context, []*Translation) error
+	ReplaceAllContext(context.Context, []*Translation) error
+}
+
+// MoveStorage is implemented by storages that can store some translations and
+// delete others as one atomic operation, translations in both are stored
+type MoveStorage interface {
+	Storage
+
+	Move(store []*Translation, remove []*Translation) error
+}
+
+// ContextMoveStorage is a MoveStorage whose operations accept a context
+type ContextMoveStorage interface {
+	MoveStorage
+
+	MoveContext(ctx context.Context, store []*Translation, remove []*Translation) error
+}
+
+// StoreMany adds translations to a storage, using StoreMany if the storage is
+// a BatchStorage
+func StoreMany(storage Storage, translations []*Translation) error {
+	return StoreManyContext(context.Background(), storage, translations)
+}
+
+// StoreManyContext adds translations to a storage as StoreMany, translations
+// are not stored once the context is done
+func StoreManyContext(ctx context.Context, storage Storage, translations []*Translation) error {
+	storage = unwrap(storage)
+
+	if batch, ok := storage.(ContextBatchStorage); ok {
+		return batch.StoreManyContext(ctx, translations)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if batch, ok := storage.(BatchStorage); ok {
+		return batch.StoreMany(translations)
+	}
+
+	s := WithContext(storage)
+	for _, translation := range translations {
+		if err := s.StoreContext(ctx, translation); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteMany removes translations from a storage, using DeleteMany if the
+// storage is a BatchStorage
+func DeleteMany(storage Storage, translations []*Translation) error {
+	return DeleteManyContext(context.Background(), storage, translations)
+}
+
+// DeleteManyContext removes translations from a storage as DeleteMany,
+// translations are not deleted once the context is done
+func DeleteManyContext(ctx context.Context, storage Storage, translations []*Translation) error {
+	storage = unwrap(storage)
+
+	if batch, ok := storage.(ContextBatchStorage); ok {
+		return batch.DeleteManyContext(ctx, translations)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if batch, ok := storage.(BatchStorage); ok {
+		return batch.DeleteMany(translations)
+	}
+
+	s := WithContext(storage)
+	for _, translation := range translations {
+		if err := s.DeleteContext(ctx, translation); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReplaceAll replaces the translations in a storage, using ReplaceAll if the
+// storage is a BatchStorage. Otherwise translations that are not being
+// replaced are deleted before the rest are stored.
+func ReplaceAll(storage Storage, translations []*Translation) error {
+	return ReplaceAllContext(context.Background(), storage, translations)
+}
+
+// ReplaceAllContext replaces the translations in a storage as ReplaceAll,
+// nothing more is written once the context is done
+func ReplaceAllContext(ctx context.Context, storage Storage, translations []*Translation) error {
+	storage = unwrap(storage)
+
+	if batch, ok := storage.(ContextBatchStorage); ok {
+		return batch.ReplaceAllContext(ctx, translations)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if batch, ok := storage.(BatchStorage); ok {
+		return batch.ReplaceAll(translations)
+	}
+
+	current, err := WithContext(storage).GetAllContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	keep := make(map[string]bool, len(translations))
+	for _, translation := range translations {
+		keep[batchKey(translation)] = true
+	}
+
+	var stale []*Translation
+	for _, translation := range current {
+		if !keep[batchKey(translation)] {
+			stale = append(stale, translation)
+		}
+	}
+
+	if err := DeleteManyContext(ctx, storage, stale); err != nil {
+		return err
+	}
+
+	return StoreManyContext(ctx, storage, translations)
+}
+
+// Move stores and deletes translations in a storage, using Move if the storage
+// is a MoveStorage. Otherwise translations are stored then deleted, and a
+// failure may leave the storage part way through.
+func Move(storage Storage, store []*Translation, remove []*Translation) error {
+	return MoveContext(context.Background(), storage, store, remove)
+}
+
+// MoveContext stores and deletes translations in a storage as Move, nothing
+// more is written once the context is done
+func MoveContext(ctx context.Context, storage Storage, store []*Translation, remove []*Translation) error {
+	storage = unwrap(storage)
+
+	if move, ok := storage.(ContextMoveStorage); ok {
+		return move.MoveContext(ctx, store, remove)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if move, ok := storage.(MoveStorage); ok {
+		return move.Move(store, remove)
+	}
+
+	stored := make(map[string]bool, len(store))
+	for _, translation := range store {
+		stored[batchKey(translation)] = true
+	}
+
+	var deleted []*Translation
+	for _, translation := range remove {
+		if !stored[batchKey(translation)] {
+			deleted = append(deleted, translation)
+		}
+	}
+
+	if err := StoreManyContext(ctx, storage, store); err != nil {
+		return err
+	}
+
+	return DeleteManyContext(ctx, storage, deleted)
+}
+
+// batchKey identifies a translation by language and key
+func batchKey(translation *Translation) string {
+	return translation.Lang.String() + "\x00" + translation.Key
+}
+
+// AddMany adds translations in as few storage operations as possible. Every
+// translation is validated and every ICU translation parsed first, nothing is
+// stored if any of them is invalid.
+func (i18n *I18n) AddMany(translations []*Translation) error {
+	return i18n.AddManyContext(context.Background(), translations)
+}
+
+// AddManyContext adds translations as AddMany, storages are not written once
+// the context is done
+func (i18n *I18n) AddManyContext(ctx context.Context, translations []*Translation) error {
+	messages, err := parseMany(translations)
+	if err != nil {
+		return err
+	}
+
+	current, err := i18n.unloaded(ctx, translations)
+	if err != nil {
+		return err
+	}
+
+	i18n.lock.Lock()
+	defer i18n.lock.Unlock()
+
+	return i18n.addMany(ctx, "AddMany", translations, messages, current)
+}
+
+// parseMany validates translations and parses their messages
+func parseMany(translations []*Translation) ([]*Message, error) {
+	messages := make([]*Message, len(translations))
+
+	for i, translation := range translations {
+		if err := translation.validate(); err != nil {
+			return nil, err
+		}
+
+		message, err := translation.message()
+		if err != nil {
+			return nil, err
+		}
+		messages[i] = message
+	}
+
+	return messages, nil
+}
+
+// addMany stores parsed translations in a single write, current holds the
+// versions read by unloaded. It must be called with the lock held.
+func (i18n *I18n) addMany(ctx context.Context, op string, translations []*Translation, messages []*Message, current map[string]*Translation) error {
+	stamped := i18n.stamp(translations, current, time.Now())
+
+	err := i18n.write(ctx, storeTranslations(op, stamped))
+	if err != nil {
+		return err
+	}
+
+	i18n.commit(stamped, messages, nil)
+
+	return nil
+}
+
+// DeleteMany deletes translations in as few storage operations as possible
+func (i18n *I18n) DeleteMany(translations []*Translation) error {
+	return i18n.DeleteManyContext(context.Background(), translations)
+}
+
+// DeleteManyContext deletes translations as DeleteMany, storages are not
+// written once the context is done
+func (i18n *I18n) DeleteManyContext(ctx context.Context, translations []*Translation) error {
+	i18n.lock.Lock()
+	defer i18n.lock.Unlock()
+
+	return i18n.deleteMany(ctx, "DeleteMany", translations)
+}
+
+// deleteMany deletes translations in a single write, it must be called with
+// the lock held
+func (i18n *I18n) deleteMany(ctx context.Context, op string, translations []*Translation) error {
+	err := i18n.write(ctx, deleteTranslations(op, translations))
+	if err != nil {
+		return err
+	}
+
+	i18n.commit(nil, nil, translations)
+
+	return nil
+}
+
+// moveMany stores and deletes translations in a single write, translations
+// in both sets are stored. It must be called with the lock held.
+func (i18n *I18n) moveMany(ctx context.Context, op string, store []*Translation, remove []*Translation) error {
+	messages := make([]*Message, len(store))
+	stored := make(map[string]bool, len(store))
+
+	for i, translation := range store {
+		message, err := translation.message()
+		if err != nil {
+			return err
+		}
+		messages[i] = message
+		stored[batchKey(translation)] = true
+	}
+
+	var deleted []*Translation
+	for _, translation := range remove {
+		if !stored[batchKey(translation)] {
+			deleted = append(deleted, translation)
+		}
+	}
+
+	err := i18n.write(ctx, moveTranslations(op, store, deleted))
+	if err != nil {
+		return err
+	}
+
+	i18n.commit(store, messages, deleted)
+
+	return nil
+}
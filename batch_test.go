@@ -0,0 +1,166 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"golang.org/x/net/context"
+	"golang.org/x/text/language"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// singleStorage hides the batch methods of a storage
+type singleStorage struct {
+	Storage
+}
+
+// countingBatchStorage counts the calls made to a batch storage
+type countingBatchStorage struct {
+	BatchStorage
+
+	stores     int
+	storeManys int
+}
+
+func (storage *countingBatchStorage) Store(translation *Translation) error {
+	storage.stores++
+	return storage.BatchStorage.Store(translation)
+}
+
+func (storage *countingBatchStorage) StoreMany(translations []*Translation) error {
+	storage.storeManys++
+	return storage.BatchStorage.StoreMany(translations)
+}
+
+func TestBatch(t *testing.T) {
+	t.Parallel()
+
+	translations := func() []*Translation {
+		return []*Translation{
+			{Lang: language.English, Key: "SomeKey", Value: "SomeValue"},
+			{Lang: language.English, Key: "OtherKey", Value: "OtherValue"},
+			{Lang: language.Spanish, Key: "SomeKey", Value: "SomeSpanishValue"},
+		}
+	}
+
+	for _, name := range []string{"batch", "non batch"} {
+		name := name
+
+		Convey("Given an empty "+name+" storage", t, func() {
+			storage := NewInMemoryStorage()
+			if name == "non batch" {
+				storage = &singleStorage{storage}
+			}
+			i18n := New(storage)
+
+			Convey("When many translations are added", func() {
+				err := i18n.AddMany(translations())
+				So(err, ShouldBeNil)
+
+				Convey("Then they should all be accessable", func() {
+					So(i18n.T(language.English, "SomeKey"), ShouldEqual, "SomeValue")
+					So(i18n.T(language.English, "OtherKey"), ShouldEqual, "OtherValue")
+					So(i18n.T(language.Spanish, "SomeKey"), ShouldEqual, "SomeSpanishValue")
+				})
+
+				Convey("Then they should all exist in the storage", func() {
+					results, err := storage.GetAll()
+					So(err, ShouldBeNil)
+					So(results, ShouldHaveLength, 3)
+				})
+
+				Convey("Then deleting many should remove them", func() {
+					err := i18n.DeleteMany(translations()[:2])
+					So(err, ShouldBeNil)
+
+					So(i18n.Get(language.English, "SomeKey"), ShouldBeNil)
+					So(i18n.Get(language.English, "OtherKey"), ShouldBeNil)
+					So(i18n.T(language.Spanish, "SomeKey"), ShouldEqual, "SomeSpanishValue")
+
+					results, err := storage.GetAll()
+					So(err, ShouldBeNil)
+					So(results, ShouldHaveLength, 1)
+				})
+
+				Convey("Then replacing all should leave only the replacements", func() {
+					err := ReplaceAll(storage, []*Translation{
+						{Lang: language.English, Key: "SomeKey", Value: "SomeOtherValue"},
+					})
+					So(err, ShouldBeNil)
+
+					results, err := storage.GetAll()
+					So(err, ShouldBeNil)
+					So(results, ShouldHaveLength, 1)
+					So(results[0].Value, ShouldEqual, "SomeOtherValue")
+				})
+			})
+
+			Convey("When many translations are added and one is invalid", func() {
+				invalid := append(translations(), &Translation{
+					Lang:  language.English,
+					Key:   "Files",
+					Value: "{count, plural, one {# file}",
+					ICU:   true,
+				})
+
+				err := i18n.AddMany(invalid)
+
+				Convey("Then none of them should be stored", func() {
+					So(err, ShouldHaveSameTypeAs, &SyntaxError{})
+
+					results, err := storage.GetAll()
+					So(err, ShouldBeNil)
+					So(results, ShouldHaveLength, 0)
+					So(i18n.Get(language.English, "SomeKey"), ShouldBeNil)
+				})
+			})
+
+			Reset(func() {
+				i18n.Close()
+			})
+		})
+	}
+
+	Convey("Given a batch storage", t, func() {
+		storage := &countingBatchStorage{BatchStorage: NewInMemoryStorage().(BatchStorage)}
+		i18n := New(storage)
+
+		Convey("When many translations are added", func() {
+			var many []*Translation
+			for i := 0; i < 100; i++ {
+				many = append(many, &Translation{Lang: language.English, Key: fmt.Sprintf("Key%d", i), Value: "Value"})
+			}
+
+			So(i18n.AddMany(many), ShouldBeNil)
+
+			Convey("Then they should be stored in one batch", func() {
+				So(storage.storeManys, ShouldEqual, 1)
+				So(storage.stores, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When many translations are added with a cancelled context", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := i18n.AddManyContext(ctx, translations())
+
+			Convey("Then nothing should be stored", func() {
+				So(errors.Is(err, context.Canceled), ShouldBeTrue)
+				So(storage.storeManys, ShouldEqual, 0)
+				So(i18n.Get(language.English, "SomeKey"), ShouldBeNil)
+			})
+		})
+
+		Convey("When many translations are stored through the context adapter", func() {
+			So(StoreMany(WithContext(storage), translations()), ShouldBeNil)
+
+			Convey("Then they should be stored in one batch", func() {
+				So(storage.storeManys, ShouldEqual, 1)
+				So(storage.stores, ShouldEqual, 0)
+			})
+		})
+	})
+}
@@ -0,0 +1,115 @@
+package i18n
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+
+	"golang.org/x/text/language"
+)
+
+// maxBundles limits how many bundles are cached in a catalog, bundles beyond
+// that are resolved on every call
+const maxBundles = 256
+
+// Bundle is every key under a prefix resolved in a language
+type Bundle struct {
+	Lang   language.Tag
+	Prefix string
+
+	// Values maps keys to their resolved values, it is shared between callers
+	// and must not be modified
+	Values map[string]string
+
+	// Hash is a stable hash of the keys and values, it changes only when the
+	// contents of the bundle change so it can be used as an ETag
+	Hash string
+}
+
+type bundleKey struct {
+	lang   language.Tag
+	prefix string
+}
+
+type bundleCache struct {
+	lock    sync.Mutex
+	bundles map[bundleKey]*Bundle
+}
+
+func newBundleCache() *bundleCache {
+	return &bundleCache{
+		bundles: make(map[bundleKey]*Bundle),
+	}
+}
+
+func (cache *bundleCache) get(key bundleKey) (*Bundle, bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	bundle, ok := cache.bundles[key]
+	return bundle, ok
+}
+
+func (cache *bundleCache) put(key bundleKey, bundle *Bundle) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	if len(cache.bundles) < maxBundles {
+		cache.bundles[key] = bundle
+	}
+}
+
+// bundle resolves every key starting with the prefix, bundles are cached until
+// the catalog is replaced
+func (c *catalog) bundle(lang language.Tag, prefix string) *Bundle {
+	key := bundleKey{lang, prefix}
+	if bundle, ok := c.bundles.get(key); ok {
+		return bundle
+	}
+
+	keys := c.index().prefixed(prefix)
+	values := make(map[string]string, len(keys))
+
+	hash := sha256.New()
+
+	for _, k := range keys {
+		if entry, _, _ := c.lookup(lang, k, false); entry != nil {
+			values[k] = entry.translation.Value
+
+			hash.Write([]byte(k))
+			hash.Write([]byte{0})
+			hash.Write([]byte(entry.translation.Value))
+			hash.Write([]byte{0})
+		}
+	}
+
+	bundle := &Bundle{
+		Lang:   lang,
+		Prefix: prefix,
+		Values: values,
+		Hash:   hex.EncodeToString(hash.Sum(nil)),
+	}
+
+	c.bundles.put(key, bundle)
+	return bundle
+}
+
+// Bundle gets the value of every key starting with the prefix resolved in the
+// language, following the fallback chain as Get does. Keys without a
+// translation in the chain are left out. Bundles are cached until the
+// translations change.
+func (i18n *I18n) Bundle(lang language.Tag, prefix string) *Bundle {
+	return i18n.loaded(lang).bundle(lang, prefix)
+}
+
+// Keys gets the sorted keys in the bundle
+func (bundle *Bundle) Keys() []string {
+	keys := make([]string, 0, len(bundle.Values))
+	for key := range bundle.Values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
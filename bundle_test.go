@@ -0,0 +1,59 @@
+package i18n
+
+import (
+	"testing"
+
+	"golang.org/x/text/language"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBundle(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given translations with a fallback", t, func() {
+		austrian := language.MustParse("de-AT")
+
+		i18n := New()
+		i18n.SetFallback(austrian, language.German)
+		i18n.AddMany([]*Translation{
+			{Lang: language.German, Key: "web.title", Value: "Willkommen"},
+			{Lang: language.German, Key: "web.button", Value: "Weiter"},
+			{Lang: austrian, Key: "web.title", Value: "Servus"},
+			{Lang: language.German, Key: "app.title", Value: "App"},
+		})
+
+		Convey("When a bundle is resolved", func() {
+			bundle := i18n.Bundle(austrian, "web.")
+
+			Convey("Then every key under the prefix should be resolved", func() {
+				So(bundle.Values, ShouldResemble, map[string]string{
+					"web.title":  "Servus",
+					"web.button": "Weiter",
+				})
+				So(bundle.Keys(), ShouldResemble, []string{"web.button", "web.title"})
+				So(bundle.Hash, ShouldNotBeEmpty)
+			})
+
+			Convey("Then it should be cached", func() {
+				So(i18n.Bundle(austrian, "web."), ShouldPointTo, bundle)
+			})
+
+			Convey("Then an unrelated change should keep the hash", func() {
+				i18n.Add(&Translation{Lang: language.German, Key: "app.other", Value: "Other"})
+
+				next := i18n.Bundle(austrian, "web.")
+				So(next, ShouldNotPointTo, bundle)
+				So(next.Hash, ShouldEqual, bundle.Hash)
+			})
+
+			Convey("Then a change to the bundle should change the hash", func() {
+				i18n.Add(&Translation{Lang: austrian, Key: "web.button", Value: "Weida"})
+
+				next := i18n.Bundle(austrian, "web.")
+				So(next.Values["web.button"], ShouldEqual, "Weida")
+				So(next.Hash, ShouldNotEqual, bundle.Hash)
+			})
+		})
+	})
+}
@@ -6,10 +6,59 @@ import (
 	"golang.org/x/text/language"
 )
 
-// Cache stores translations in a map for fast access
+type cacheEntry struct {
+	translation *Translation
+	message     *Message
+
+	// approved is the entry of the approved version kept while a revision
+	// is in review
+	approved *cacheEntry
+}
+
+func newCacheEntry(translation *Translation, message *Message) *cacheEntry {
+	entry := &cacheEntry{
+		translation: translation,
+		message:     message,
+	}
+
+	if translation.Approved != nil {
+		if message, err := translation.Approved.message(); err == nil {
+			entry.approved = &cacheEntry{
+				translation: translation.Approved,
+				message:     message,
+			}
+		}
+	}
+
+	return entry
+}
+
+// serve gets the entry served in a preview mode, the approved version is
+// served while a revision that is not served is in review
+func (entry *cacheEntry) serve(preview bool) *cacheEntry {
+	if entry.translation.servable(preview) {
+		return entry
+	}
+	return entry.approved
+}
+
+func (entry *cacheEntry) format(args []interface{}) (string, error) {
+	if entry.message == nil {
+		return Format(entry.translation.Value, args...), nil
+	}
+
+	return entry.message.Format(entry.translation.Lang, collectArgs(args))
+}
+
+// Cache stores translations in a map for fast access, ICU translations are
+// stored alongside their parsed message
+//
+// Deprecated: I18n no longer uses Cache, translations are kept in a catalog
+// keyed by compact language ids with precomputed fallback chains. Cache is kept
+// for existing callers and will be removed in a future release.
 type Cache struct {
 	lock  sync.RWMutex
-	cache map[string]map[string]*Translation
+	cache map[string]map[string]*cacheEntry
 }
 
 // Clear translation cache
@@ -17,29 +66,44 @@ func (cache *Cache) Clear() {
 	cache.lock.Lock()
 	defer cache.lock.Unlock()
 
-	cache.cache = make(map[string]map[string]*Translation)
+	cache.cache = make(map[string]map[string]*cacheEntry)
 }
 
-// Add translation to cache
+// Add translation to cache, ICU translations that can not be parsed are
+// stored without a message
 func (cache *Cache) Add(translation *Translation) {
+	cache.AddChecked(translation)
+}
+
+// AddChecked adds a translation to cache as Add, returning the SyntaxError of
+// an ICU translation that can not be parsed
+func (cache *Cache) AddChecked(translation *Translation) error {
+	message, err := translation.message()
+	cache.add(translation, message)
+	return err
+}
+
+func (cache *Cache) add(translation *Translation, message *Message) {
 	cache.lock.Lock()
 	defer cache.lock.Unlock()
 
 	if cache.cache == nil {
-		cache.cache = make(map[string]map[string]*Translation)
+		cache.cache = make(map[string]map[string]*cacheEntry)
 	}
 
 	l := translation.Lang.String()
 
 	if _, ok := cache.cache[l]; !ok {
-		cache.cache[l] = make(map[string]*Translation)
+		cache.cache[l] = make(map[string]*cacheEntry)
 	}
 
-	cache.cache[l][translation.Key] = translation
+	cache.cache[l][translation.Key] = &cacheEntry{
+		translation: translation,
+		message:     message,
+	}
 }
 
-// Get translation from cache
-func (cache *Cache) Get(lang language.Tag, key string) *Translation {
+func (cache *Cache) entry(lang language.Tag, key string) *cacheEntry {
 	cache.lock.RLock()
 	defer cache.lock.RUnlock()
 
@@ -60,6 +124,22 @@ func (cache *Cache) Get(lang language.Tag, key string) *Translation {
 	return cache.cache[l][key]
 }
 
+// Get translation from cache
+func (cache *Cache) Get(lang language.Tag, key string) *Translation {
+	if entry := cache.entry(lang, key); entry != nil {
+		return entry.translation
+	}
+	return nil
+}
+
+// Message gets the parsed ICU message of a translation from cache
+func (cache *Cache) Message(lang language.Tag, key string) *Message {
+	if entry := cache.entry(lang, key); entry != nil {
+		return entry.message
+	}
+	return nil
+}
+
 // Delete translation from cache
 func (cache *Cache) Delete(translation *Translation) {
 	cache.lock.Lock()
@@ -29,6 +29,41 @@ func TestCache(t *testing.T) {
 		})
 	})
 
+	Convey("Given an empty cache", t, func() {
+		cache := new(Cache)
+		Convey("When an ICU translation is added to the cache", func() {
+			cache.Add(&Translation{
+				Lang:  language.English,
+				Key:   "SomeKey",
+				Value: "Hello {name}",
+				ICU:   true,
+			})
+
+			Convey("Then the parsed message should be accessable", func() {
+				result := cache.Message(language.English, "SomeKey")
+				So(result, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When an invalid ICU translation is added to the cache", func() {
+			err := cache.AddChecked(&Translation{
+				Lang:  language.English,
+				Key:   "SomeKey",
+				Value: "Hello {name",
+				ICU:   true,
+			})
+
+			Convey("Then the syntax error should be returned", func() {
+				So(err, ShouldHaveSameTypeAs, &SyntaxError{})
+			})
+
+			Convey("Then it should be stored without a message", func() {
+				So(cache.Get(language.English, "SomeKey"), ShouldNotBeNil)
+				So(cache.Message(language.English, "SomeKey"), ShouldBeNil)
+			})
+		})
+	})
+
 	Convey("Given a populated cache", t, func() {
 		cache := new(Cache)
 		cache.Add(&Translation{
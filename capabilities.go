@@ -0,0 +1,64 @@
+package i18n
+
+import (
+	"strings"
+)
+
+// Capabilities describes which writes a storage supports
+type Capabilities struct {
+	// Writable storages accept translation writes
+	Writable bool
+
+	// Languages storages accept supported language, default language and
+	// fallback chain writes
+	Languages bool
+}
+
+// ReadOnly reports whether the storage accepts no writes
+func (c Capabilities) ReadOnly() bool {
+	return !c.Writable && !c.Languages
+}
+
+// CapableStorage is implemented by storages that declare their capabilities,
+// storages that do not are assumed to support every write
+type CapableStorage interface {
+	Storage
+	Capabilities() Capabilities
+}
+
+// CapabilitiesOf gets the capabilities of a storage
+func CapabilitiesOf(storage Storage) Capabilities {
+	if capable, ok := unwrap(storage).(CapableStorage); ok {
+		return capable.Capabilities()
+	}
+
+	return Capabilities{
+		Writable:  true,
+		Languages: true,
+	}
+}
+
+// WriteError is returned when no storage accepts a write, it holds the reason
+// each storage did not
+type WriteError struct {
+	Op     string
+	Errors []error
+}
+
+func (err *WriteError) Error() string {
+	reasons := make([]string, 0, len(err.Errors))
+	for _, e := range err.Errors {
+		reasons = append(reasons, e.Error())
+	}
+
+	if len(reasons) == 0 {
+		return "i18n: " + err.Op + ": no storage accepted the change"
+	}
+
+	return "i18n: " + err.Op + ": no storage accepted the change: " + strings.Join(reasons, "; ")
+}
+
+// Unwrap returns the error from each storage
+func (err *WriteError) Unwrap() []error {
+	return err.Errors
+}
@@ -0,0 +1,118 @@
+package i18n
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/text/language"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// readOnlyStorage declares itself read only
+type readOnlyStorage struct {
+	Storage
+}
+
+func (storage *readOnlyStorage) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
+// rejectingStorage does not declare capabilities but rejects every write
+type rejectingStorage struct {
+	Storage
+}
+
+func (storage *rejectingStorage) Store(*Translation) error {
+	return ErrReadOnly
+}
+
+func TestCapabilities(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a read only storage combined with a writable storage", t, func() {
+		readOnly := &readOnlyStorage{NewInMemoryStorage()}
+		writable := NewInMemoryStorage()
+		i18n := New(readOnly, &rejectingStorage{NewInMemoryStorage()}, writable)
+
+		Convey("Then the capabilities should be reported", func() {
+			So(CapabilitiesOf(readOnly).ReadOnly(), ShouldBeTrue)
+			So(CapabilitiesOf(writable).ReadOnly(), ShouldBeFalse)
+		})
+
+		Convey("When a translation is added", func() {
+			err := i18n.Add(&Translation{
+				Lang:  language.English,
+				Key:   "SomeKey",
+				Value: "SomeValue",
+			})
+
+			Convey("Then it should only be written to the writable storage", func() {
+				So(err, ShouldBeNil)
+				So(i18n.T(language.English, "SomeKey"), ShouldEqual, "SomeValue")
+
+				results, err := writable.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+
+				results, err = readOnly.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("When the default language is set", func() {
+			err := i18n.SetDefaultLanguage(language.English)
+
+			Convey("Then it should be skipped by the read only storage", func() {
+				So(err, ShouldBeNil)
+				So(i18n.GetDefaultLanguage(), ShouldResemble, language.English)
+
+				def, err := readOnly.DefaultLanguage()
+				So(err, ShouldBeNil)
+				So(def, ShouldResemble, language.Und)
+			})
+		})
+
+		Reset(func() {
+			i18n.Close()
+		})
+	})
+
+	Convey("Given only storages that reject translations", t, func() {
+		i18n := New(&readOnlyStorage{NewInMemoryStorage()}, &rejectingStorage{NewInMemoryStorage()})
+
+		Convey("When a translation is added", func() {
+			err := i18n.Add(&Translation{
+				Lang:  language.English,
+				Key:   "SomeKey",
+				Value: "SomeValue",
+			})
+
+			Convey("Then an error for every storage should be returned", func() {
+				var writeErr *WriteError
+				So(errors.As(err, &writeErr), ShouldBeTrue)
+				So(writeErr.Op, ShouldEqual, "Add")
+				So(writeErr.Errors, ShouldHaveLength, 2)
+				So(errors.Is(err, ErrReadOnly), ShouldBeTrue)
+			})
+
+			Convey("Then it should not be accessable", func() {
+				So(i18n.Get(language.English, "SomeKey"), ShouldBeNil)
+			})
+		})
+
+		Convey("When the default language is set", func() {
+			err := i18n.SetDefaultLanguage(language.English)
+
+			Convey("Then it should be written to the storage that accepts it", func() {
+				So(err, ShouldBeNil)
+				So(i18n.GetDefaultLanguage(), ShouldResemble, language.English)
+			})
+		})
+
+		Reset(func() {
+			i18n.Close()
+		})
+	})
+}
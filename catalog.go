@@ -0,0 +1,306 @@
+package i18n
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/text/language"
+)
+
+// maxChains limits how many requested languages have their fallback chain
+// precomputed in a catalog, chains beyond that are resolved on every lookup
+const maxChains = 1024
+
+// catalog is an immutable snapshot of everything needed to resolve a
+// translation. Readers load the current catalog without locking, writers
+// build a modified copy and publish it in its place.
+//
+// Each language with translations is given a compact id, entries are indexed
+// by that id and then keyed by the translation key. The ids of the fallback
+// chain of a requested language are computed once per catalog, so a lookup in
+// steady state needs neither tag.String() nor any allocation.
+type catalog struct {
+	langs   map[string]int
+	entries []map[string]*cacheEntry
+	chains  *chainCache
+	keys    *keyIndex
+	bundles *bundleCache
+
+	defaultLanguage    language.Tag
+	supportedLanguages []language.Tag
+
+	fallbacks         map[string][]language.Tag
+	fallbackToDefault bool
+
+	missingKeyHandler MissingKeyHandler
+	observer          Observer
+	logger            Logger
+
+	// lazy mode, loaded holds the estimated size of each language loaded
+	lazy   lazyConfig
+	loaded map[string]int
+	ready  *readyCache
+	usage  *languageUsage
+}
+
+// chainStep is a language in a fallback chain that has translations
+type chainStep struct {
+	tag   language.Tag
+	id    int
+	depth int
+}
+
+type chainCache struct {
+	lock   sync.Mutex
+	chains atomic.Value
+}
+
+func newChainCache() *chainCache {
+	cache := new(chainCache)
+	cache.chains.Store(make(map[language.Tag][]chainStep))
+	return cache
+}
+
+func (cache *chainCache) get(tag language.Tag) ([]chainStep, bool) {
+	steps, ok := cache.chains.Load().(map[language.Tag][]chainStep)[tag]
+	return steps, ok
+}
+
+func (cache *chainCache) put(tag language.Tag, steps []chainStep) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	current := cache.chains.Load().(map[language.Tag][]chainStep)
+	if len(current) >= maxChains {
+		return
+	}
+
+	next := make(map[language.Tag][]chainStep, len(current)+1)
+	for t, s := range current {
+		next[t] = s
+	}
+	next[tag] = steps
+
+	cache.chains.Store(next)
+}
+
+func newCatalog() *catalog {
+	return &catalog{
+		langs:     make(map[string]int),
+		chains:    newChainCache(),
+		keys:      new(keyIndex),
+		bundles:   newBundleCache(),
+		fallbacks: make(map[string][]language.Tag),
+		loaded:    make(map[string]int),
+		ready:     newReadyCache(),
+		usage:     newLanguageUsage(),
+	}
+}
+
+// clone makes a shallow copy of the catalog, maps and slices must be copied
+// before they are modified. Precomputed chains, the key index, bundles and
+// the chains known to be loaded in lazy mode are not carried over.
+func (c *catalog) clone() *catalog {
+	next := *c
+	next.chains = newChainCache()
+	next.keys = new(keyIndex)
+	next.bundles = newBundleCache()
+	next.ready = newReadyCache()
+	return &next
+}
+
+// put adds a translation in place, it must only be used on a catalog that has
+// not been published
+func (c *catalog) put(translation *Translation, message *Message) {
+	l := translation.Lang.String()
+
+	id, ok := c.langs[l]
+	if !ok {
+		id = len(c.entries)
+		c.langs[l] = id
+		c.entries = append(c.entries, make(map[string]*cacheEntry))
+	}
+
+	c.entries[id][translation.Key] = newCacheEntry(translation, message)
+}
+
+func (c *catalog) withTranslation(translation *Translation, message *Message) *catalog {
+	return c.withTranslations([]*Translation{translation}, []*Message{message})
+}
+
+// withTranslations adds translations along with their parsed messages, only
+// the languages that change are copied
+func (c *catalog) withTranslations(translations []*Translation, messages []*Message) *catalog {
+	next := c.clone()
+
+	next.entries = make([]map[string]*cacheEntry, len(c.entries), len(c.entries)+1)
+	copy(next.entries, c.entries)
+
+	copied := make(map[int]bool)
+	copiedLangs := false
+
+	for i, translation := range translations {
+		id, ok := next.langs[translation.Lang.String()]
+
+		if !ok && !copiedLangs {
+			next.langs = make(map[string]int, len(c.langs)+1)
+			for lang, id := range c.langs {
+				next.langs[lang] = id
+			}
+			copiedLangs = true
+		}
+
+		if ok && id < len(c.entries) && !copied[id] {
+			entries := make(map[string]*cacheEntry, len(c.entries[id])+1)
+			for k, entry := range c.entries[id] {
+				entries[k] = entry
+			}
+			next.entries[id] = entries
+			copied[id] = true
+		}
+
+		next.put(translation, messages[i])
+	}
+
+	return next
+}
+
+func (c *catalog) withoutTranslation(translation *Translation) *catalog {
+	return c.withoutTranslations([]*Translation{translation})
+}
+
+// withoutTranslations removes translations, the catalog is returned unchanged
+// if none of them exist
+func (c *catalog) withoutTranslations(translations []*Translation) *catalog {
+	var next *catalog
+	copied := make(map[int]bool)
+
+	for _, translation := range translations {
+		id, ok := c.langs[translation.Lang.String()]
+		if !ok {
+			continue
+		}
+
+		if _, ok := c.entries[id][translation.Key]; !ok {
+			continue
+		}
+
+		if next == nil {
+			next = c.clone()
+			next.entries = make([]map[string]*cacheEntry, len(c.entries))
+			copy(next.entries, c.entries)
+		}
+
+		if !copied[id] {
+			entries := make(map[string]*cacheEntry, len(c.entries[id]))
+			for k, entry := range c.entries[id] {
+				entries[k] = entry
+			}
+			next.entries[id] = entries
+			copied[id] = true
+		}
+
+		delete(next.entries[id], translation.Key)
+	}
+
+	if next == nil {
+		return c
+	}
+
+	return next
+}
+
+func (c *catalog) withSupportedLanguage(tag language.Tag) *catalog {
+	for _, lang := range c.supportedLanguages {
+		if lang.String() == tag.String() {
+			return c
+		}
+	}
+
+	next := c.clone()
+	next.supportedLanguages = make([]language.Tag, 0, len(c.supportedLanguages)+1)
+	next.supportedLanguages = append(next.supportedLanguages, c.supportedLanguages...)
+	next.supportedLanguages = append(next.supportedLanguages, tag)
+
+	return next
+}
+
+func (c *catalog) withoutSupportedLanguage(tag language.Tag) *catalog {
+	next := c.clone()
+	next.supportedLanguages = make([]language.Tag, 0, len(c.supportedLanguages))
+
+	for _, lang := range c.supportedLanguages {
+		if lang.String() != tag.String() {
+			next.supportedLanguages = append(next.supportedLanguages, lang)
+		}
+	}
+
+	return next
+}
+
+func (c *catalog) withFallback(tag language.Tag, chain []language.Tag) *catalog {
+	next := c.clone()
+	next.fallbacks = make(map[string][]language.Tag, len(c.fallbacks)+1)
+	for l, f := range c.fallbacks {
+		next.fallbacks[l] = f
+	}
+
+	next.fallbacks[tag.String()] = chain
+
+	return next
+}
+
+func (c *catalog) withoutFallback(tag language.Tag) *catalog {
+	next := c.clone()
+	next.fallbacks = make(map[string][]language.Tag, len(c.fallbacks))
+	for l, f := range c.fallbacks {
+		if l != tag.String() {
+			next.fallbacks[l] = f
+		}
+	}
+
+	return next
+}
+
+func (c *catalog) entry(lang language.Tag, key string) *cacheEntry {
+	if id, ok := c.langs[lang.String()]; ok {
+		return c.entries[id][key]
+	}
+	return nil
+}
+
+// steps gets the languages of the fallback chain that have translations
+func (c *catalog) steps(lang language.Tag) []chainStep {
+	if steps, ok := c.chains.get(lang); ok {
+		return steps
+	}
+
+	var steps []chainStep
+	for depth, tag := range c.chain(lang) {
+		if id, ok := c.langs[tag.String()]; ok {
+			steps = append(steps, chainStep{
+				tag:   tag,
+				id:    id,
+				depth: depth,
+			})
+		}
+	}
+
+	c.chains.put(lang, steps)
+	return steps
+}
+
+// lookup walks the fallback chain returning the entry served, the language it
+// was found in and its position in the chain. Entries with nothing served in
+// the preview mode given are skipped.
+func (c *catalog) lookup(lang language.Tag, key string, preview bool) (*cacheEntry, language.Tag, int) {
+	for _, step := range c.steps(lang) {
+		if entry, ok := c.entries[step.id][key]; ok {
+			if served := entry.serve(preview); served != nil {
+				return served, step.tag, step.depth
+			}
+		}
+	}
+
+	return nil, language.Und, 0
+}
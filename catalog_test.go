@@ -0,0 +1,195 @@
+package i18n
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"golang.org/x/text/language"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func populatedI18n(n int) *I18n {
+	storage := NewInMemoryStorage()
+	for i := 0; i < n; i++ {
+		storage.Store(&Translation{
+			Lang:  language.English,
+			Key:   fmt.Sprintf("Key%d", i),
+			Value: fmt.Sprintf("Value%d", i),
+		})
+	}
+
+	i18n := New(storage)
+	i18n.Sync()
+	return i18n
+}
+
+func TestCatalog(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a populated store that is being synced", t, func() {
+		i18n := populatedI18n(1000)
+
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					i18n.Sync()
+				}
+			}
+		}()
+
+		Convey("When translations are read concurrently", func() {
+			missing := 0
+			for i := 0; i < 10000; i++ {
+				if i18n.T(language.BritishEnglish, fmt.Sprintf("Key%d", i%1000)) == "" {
+					missing++
+				}
+			}
+
+			Convey("Then no read should see a partial catalog", func() {
+				So(missing, ShouldEqual, 0)
+			})
+		})
+
+		Reset(func() {
+			close(stop)
+			wg.Wait()
+		})
+	})
+
+	Convey("Given a catalog", t, func() {
+		c := newCatalog()
+		translation := &Translation{
+			Lang:  language.English,
+			Key:   "SomeKey",
+			Value: "SomeValue",
+		}
+
+		Convey("When a translation is added", func() {
+			next := c.withTranslation(translation, nil)
+
+			Convey("Then the original catalog should not change", func() {
+				So(c.entry(language.English, "SomeKey"), ShouldBeNil)
+				So(next.entry(language.English, "SomeKey"), ShouldNotBeNil)
+			})
+
+			Convey("Then removing it should not change the catalog it was added to", func() {
+				removed := next.withoutTranslation(translation)
+				So(removed.entry(language.English, "SomeKey"), ShouldBeNil)
+				So(next.entry(language.English, "SomeKey"), ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+// AllocsPerRun can not be used in parallel tests
+func TestCatalogAllocations(t *testing.T) {
+	Convey("Given a populated store", t, func() {
+		i18n := populatedI18n(10)
+
+		Convey("When translations are read in steady state", func() {
+			i18n.T(language.BritishEnglish, "Key1")
+
+			allocs := testing.AllocsPerRun(100, func() {
+				i18n.T(language.BritishEnglish, "Key1")
+				i18n.T(language.BritishEnglish, "Missing")
+			})
+
+			Convey("Then no allocations should be made", func() {
+				So(allocs, ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func BenchmarkCacheGet(b *testing.B) {
+	cache := new(Cache)
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("Key%d", i)
+		cache.Add(&Translation{
+			Lang:  language.English,
+			Key:   keys[i],
+			Value: keys[i],
+		})
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for lang := language.BritishEnglish; ; lang = lang.Parent() {
+			if cache.Get(lang, keys[i%1000]) != nil || lang.IsRoot() {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkCatalogT(b *testing.B) {
+	i18n := populatedI18n(1000)
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("Key%d", i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		i18n.T(language.BritishEnglish, keys[i%1000])
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	i18n := populatedI18n(1000)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			i18n.T(language.BritishEnglish, fmt.Sprintf("Key%d", i%1000))
+			i++
+		}
+	})
+}
+
+func BenchmarkGetDuringSync(b *testing.B) {
+	i18n := populatedI18n(1000)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				i18n.Sync()
+			}
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			i18n.T(language.BritishEnglish, fmt.Sprintf("Key%d", i%1000))
+			i++
+		}
+	})
+	b.StopTimer()
+
+	close(stop)
+	wg.Wait()
+}
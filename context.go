@@ -0,0 +1,53 @@
+package i18n
+
+import (
+	"golang.org/x/net/context"
+)
+
+// Options are request scoped translation options carried in a context
+type Options struct {
+	// MissingKeyHandler takes precedence over the handler set on I18n
+	MissingKeyHandler MissingKeyHandler
+
+	// Preview serves drafts and translations waiting for review as well as
+	// approved translations
+	Preview bool
+}
+
+// NewOptionsContext stores translation options in the context
+func NewOptionsContext(ctx context.Context, options *Options) context.Context {
+	return context.WithValue(ctx, "i18n_options", options)
+}
+
+// GetOptionsFromContext returns the translation options from the context, or
+// nil if there are none
+func GetOptionsFromContext(ctx context.Context) *Options {
+	if options, ok := ctx.Value("i18n_options").(*Options); ok {
+		return options
+	}
+	return nil
+}
+
+// NewMissingKeyContext stores a missing key handler in the context, it is used
+// for the request in place of the handler set on I18n
+func NewMissingKeyContext(ctx context.Context, handler MissingKeyHandler) context.Context {
+	options := Options{}
+	if current := GetOptionsFromContext(ctx); current != nil {
+		options = *current
+	}
+
+	options.MissingKeyHandler = handler
+	return NewOptionsContext(ctx, &options)
+}
+
+// NewPreviewContext stores preview mode in the context, drafts and
+// translations waiting for review are served for the request
+func NewPreviewContext(ctx context.Context) context.Context {
+	options := Options{}
+	if current := GetOptionsFromContext(ctx); current != nil {
+		options = *current
+	}
+
+	options.Preview = true
+	return NewOptionsContext(ctx, &options)
+}
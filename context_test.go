@@ -0,0 +1,58 @@
+package i18n
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"golang.org/x/text/language"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestContext(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a language stored in the context", t, func() {
+		i18n := New()
+		i18n.Add(&Translation{
+			Lang:  language.English,
+			Key:   "SomeGroup.Greeting",
+			Value: "Hello {name}",
+		})
+		i18n.Add(&Translation{
+			Lang:  language.English,
+			Key:   "SomeGroup.Files",
+			Value: "files",
+			Plurals: map[PluralForm]string{
+				PluralOne: "file",
+			},
+		})
+		group := i18n.Group("SomeGroup")
+
+		ctx := NewLanguageContext(context.Background(), language.BritishEnglish)
+
+		Convey("Then translations should be resolved in that language", func() {
+			So(i18n.TCtx(ctx, "SomeGroup.Greeting", Args{"name": "Bob"}), ShouldEqual, "Hello Bob")
+			So(group.TCtx(ctx, "Greeting", Args{"name": "Bob"}), ShouldEqual, "Hello Bob")
+			So(group.PluralCtx(ctx, "Files", 1), ShouldEqual, "file")
+
+			result, err := group.FormatCtx(ctx, "Greeting", Args{"name": "Bob"})
+			So(err, ShouldBeNil)
+			So(result, ShouldEqual, "Hello Bob")
+		})
+
+		Convey("When a missing key handler is stored in the context", func() {
+			i18n.SetMissingKeyHandler(MissingKeyReturnKey)
+			ctx := NewMissingKeyContext(ctx, MissingKeyMarker)
+
+			Convey("Then it should be used in place of the handler set on I18n", func() {
+				So(i18n.TCtx(ctx, "OtherKey"), ShouldEqual, "[[missing:OtherKey]]")
+				So(group.PluralCtx(ctx, "OtherKey", 1), ShouldEqual, "[[missing:SomeGroup.OtherKey]]")
+			})
+
+			Convey("Then other contexts should use the handler set on I18n", func() {
+				So(i18n.TCtx(context.Background(), "OtherKey"), ShouldEqual, "OtherKey")
+			})
+		})
+	})
+}
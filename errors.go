@@ -0,0 +1,76 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/text/language"
+)
+
+var (
+	// ErrNotFound is returned when a translation or value does not exist
+	ErrNotFound = errors.New("i18n: not found")
+
+	// ErrReadOnly is returned when writing to a storage that does not support
+	// writes
+	ErrReadOnly = errors.New("i18n: storage is read only")
+
+	// ErrInvalidLanguage is returned when a language string can not be parsed
+	ErrInvalidLanguage = errors.New("i18n: invalid language")
+
+	// ErrUnsupported is returned by storages that wrap others, such as
+	// LayeredStorage, when the wrapped storage does not support an operation
+	ErrUnsupported = errors.New("i18n: operation not supported by storage")
+)
+
+// StorageError records a failed storage operation along with the backend it
+// failed in
+type StorageError struct {
+	Backend string
+	Op      string
+	Err     error
+}
+
+func (err *StorageError) Error() string {
+	return err.Backend + " " + err.Op + ": " + err.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (err *StorageError) Unwrap() error {
+	return err.Err
+}
+
+// LanguageError records a language string that could not be parsed, it matches
+// ErrInvalidLanguage
+type LanguageError struct {
+	Lang string
+	Err  error
+}
+
+func (err *LanguageError) Error() string {
+	return fmt.Sprintf("i18n: invalid language %q: %s", err.Lang, err.Err)
+}
+
+// Unwrap returns the underlying parse error
+func (err *LanguageError) Unwrap() error {
+	return err.Err
+}
+
+// Is reports whether target is ErrInvalidLanguage
+func (err *LanguageError) Is(target error) bool {
+	return target == ErrInvalidLanguage
+}
+
+// ParseLanguage parses a language string, returning a LanguageError if it is
+// invalid
+func ParseLanguage(lang string) (language.Tag, error) {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return language.Und, &LanguageError{
+			Lang: lang,
+			Err:  err,
+		}
+	}
+
+	return tag, nil
+}
@@ -0,0 +1,64 @@
+package i18n
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/text/language"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestErrors(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a populated memory store", t, func() {
+		i18n := New(NewInMemoryStorage())
+		i18n.Add(&Translation{
+			Lang:  language.English,
+			Key:   "SomeKey",
+			Value: "SomeValue",
+		})
+
+		Convey("When an invalid language string is used", func() {
+			_, err := i18n.GetWithLangString("not a language", "SomeKey")
+
+			Convey("Then an invalid language error should be returned", func() {
+				var langErr *LanguageError
+				So(errors.Is(err, ErrInvalidLanguage), ShouldBeTrue)
+				So(errors.As(err, &langErr), ShouldBeTrue)
+				So(langErr.Lang, ShouldEqual, "not a language")
+			})
+		})
+
+		Convey("When a missing key is requested", func() {
+			result, err := i18n.GetWithLangString("en", "OtherKey")
+
+			Convey("Then nil should be returned without an error", func() {
+				So(result, ShouldBeNil)
+				So(err, ShouldBeNil)
+			})
+		})
+
+		Reset(func() {
+			i18n.Close()
+		})
+	})
+
+	Convey("Given a storage error", t, func() {
+		err := &StorageError{
+			Backend: "redis",
+			Op:      "Store",
+			Err:     ErrReadOnly,
+		}
+
+		Convey("Then it should name the backend and operation", func() {
+			So(err.Error(), ShouldEqual, "redis Store: i18n: storage is read only")
+		})
+
+		Convey("Then it should match the underlying error", func() {
+			So(errors.Is(err, ErrReadOnly), ShouldBeTrue)
+			So(errors.Is(err, ErrNotFound), ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,136 @@
+package i18n
+
+import (
+	"golang.org/x/net/context"
+	"golang.org/x/text/language"
+)
+
+// Fallback is a list of languages tried, in order, when a translation is
+// missing in a language and its parents
+type Fallback struct {
+	Lang  language.Tag
+	Chain []language.Tag
+}
+
+func (fallback *Fallback) copy() *Fallback {
+	return &Fallback{
+		Lang:  fallback.Lang,
+		Chain: append([]language.Tag(nil), fallback.Chain...),
+	}
+}
+
+// FallbackStorage is implemented by storages that persist fallback chains and
+// whether chains end at the default language. Fallback chains set on an I18n
+// are only kept in memory when none of its storages implement it.
+type FallbackStorage interface {
+	Storage
+
+	Fallbacks() ([]*Fallback, error)
+	StoreFallback(*Fallback) error
+	DeleteFallback(language.Tag) error
+
+	FallbackToDefault() (bool, error)
+	SetFallbackToDefault(bool) error
+}
+
+// ContextFallbackStorage is a FallbackStorage whose operations accept a
+// context
+type ContextFallbackStorage interface {
+	FallbackStorage
+
+	FallbacksContext(context.Context) ([]*Fallback, error)
+	StoreFallbackContext(context.Context, *Fallback) error
+	DeleteFallbackContext(context.Context, language.Tag) error
+
+	FallbackToDefaultContext(context.Context) (bool, error)
+	SetFallbackToDefaultContext(context.Context, bool) error
+}
+
+// WithFallbackContext returns the storage as a ContextFallbackStorage, adapting
+// it as WithContext does. False is returned if the storage does not implement
+// FallbackStorage.
+func WithFallbackContext(storage Storage) (ContextFallbackStorage, bool) {
+	switch s := unwrap(storage).(type) {
+	case ContextFallbackStorage:
+		return s, true
+	case FallbackStorage:
+		return &contextFallbackStorage{s}, true
+	}
+	return nil, false
+}
+
+type contextFallbackStorage struct {
+	FallbackStorage
+}
+
+func (storage *contextFallbackStorage) FallbacksContext(ctx context.Context) ([]*Fallback, error) {
+	var fallbacks []*Fallback
+	err := Await(ctx, func() (err error) {
+		fallbacks, err = storage.Fallbacks()
+		return err
+	})
+	return fallbacks, err
+}
+
+func (storage *contextFallbackStorage) StoreFallbackContext(ctx context.Context, fallback *Fallback) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return storage.StoreFallback(fallback)
+}
+
+func (storage *contextFallbackStorage) DeleteFallbackContext(ctx context.Context, tag language.Tag) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return storage.DeleteFallback(tag)
+}
+
+func (storage *contextFallbackStorage) FallbackToDefaultContext(ctx context.Context) (bool, error) {
+	var enabled bool
+	err := Await(ctx, func() (err error) {
+		enabled, err = storage.FallbackToDefault()
+		return err
+	})
+	return enabled, err
+}
+
+func (storage *contextFallbackStorage) SetFallbackToDefaultContext(ctx context.Context, enabled bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return storage.SetFallbackToDefault(enabled)
+}
+
+// chain resolves the languages to look a translation up in. The language and
+// its parents come first, each followed by their configured fallbacks, then the
+// default language if enabled and finally the root language.
+func (c *catalog) chain(lang language.Tag) []language.Tag {
+	seen := make(map[string]bool)
+	var chain []language.Tag
+
+	var walk func(tag language.Tag)
+	walk = func(tag language.Tag) {
+		for ; !tag.IsRoot(); tag = tag.Parent() {
+			str := tag.String()
+			if seen[str] {
+				continue
+			}
+
+			seen[str] = true
+			chain = append(chain, tag)
+
+			for _, fallback := range c.fallbacks[str] {
+				walk(fallback)
+			}
+		}
+	}
+
+	walk(lang)
+
+	if c.fallbackToDefault {
+		walk(c.defaultLanguage)
+	}
+
+	return append(chain, language.Und)
+}
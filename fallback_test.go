@@ -0,0 +1,132 @@
+package i18n
+
+import (
+	"testing"
+
+	"golang.org/x/text/language"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFallback(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given translations in several languages", t, func() {
+		storage := NewInMemoryStorage().(FallbackStorage)
+		i18n := New(storage)
+
+		i18n.SetDefaultLanguage(language.English)
+		i18n.Add(&Translation{Lang: language.English, Key: "English", Value: "en"})
+		i18n.Add(&Translation{Lang: language.Spanish, Key: "Spanish", Value: "es"})
+		i18n.Add(&Translation{Lang: language.Portuguese, Key: "Portuguese", Value: "pt"})
+
+		Convey("When no fallback is configured", func() {
+			Convey("Then only the parent languages should be used", func() {
+				So(i18n.T(language.BrazilianPortuguese, "Portuguese"), ShouldEqual, "pt")
+				So(i18n.Get(language.BrazilianPortuguese, "Spanish"), ShouldBeNil)
+				So(i18n.Get(language.BrazilianPortuguese, "English"), ShouldBeNil)
+			})
+		})
+
+		Convey("When a fallback chain is configured", func() {
+			err := i18n.SetFallback(language.BrazilianPortuguese, language.Portuguese, language.Spanish)
+			So(err, ShouldBeNil)
+
+			Convey("Then the chain should be resolved in order", func() {
+				chain := i18n.GetFallbackChain(language.BrazilianPortuguese)
+				So(chain, ShouldResemble, []language.Tag{
+					language.BrazilianPortuguese,
+					language.Portuguese,
+					language.Spanish,
+					language.Und,
+				})
+			})
+
+			Convey("Then missing translations should fall back along the chain", func() {
+				So(i18n.T(language.BrazilianPortuguese, "Spanish"), ShouldEqual, "es")
+				So(i18n.Get(language.BrazilianPortuguese, "English"), ShouldBeNil)
+			})
+
+			Convey("Then the chain should be persisted in storage", func() {
+				fallbacks, err := storage.Fallbacks()
+				So(err, ShouldBeNil)
+				So(fallbacks, ShouldHaveLength, 1)
+				So(fallbacks[0].Chain, ShouldHaveLength, 2)
+			})
+
+			Convey("Then another instance should resolve the same way after a sync", func() {
+				other := New(storage)
+				So(other.Sync(), ShouldBeNil)
+				So(other.T(language.BrazilianPortuguese, "Spanish"), ShouldEqual, "es")
+			})
+
+			Convey("Then removing the chain should stop the fallback", func() {
+				So(i18n.RemoveFallback(language.BrazilianPortuguese), ShouldBeNil)
+				So(i18n.Get(language.BrazilianPortuguese, "Spanish"), ShouldBeNil)
+			})
+		})
+
+		Convey("When the slice passed as a fallback chain is changed afterwards", func() {
+			chain := []language.Tag{language.Portuguese, language.Spanish}
+			So(i18n.SetFallback(language.BrazilianPortuguese, chain...), ShouldBeNil)
+
+			chain[0] = language.English
+			i18n.GetFallback(language.BrazilianPortuguese)[1] = language.English
+
+			Convey("Then the configured chain should not change", func() {
+				So(i18n.GetFallback(language.BrazilianPortuguese), ShouldResemble, []language.Tag{language.Portuguese, language.Spanish})
+
+				fallbacks, err := storage.Fallbacks()
+				So(err, ShouldBeNil)
+				So(fallbacks[0].Chain, ShouldResemble, []language.Tag{language.Portuguese, language.Spanish})
+			})
+		})
+
+		Convey("When fallbacks refer to each other", func() {
+			i18n.SetFallback(language.Spanish, language.Portuguese)
+			i18n.SetFallback(language.Portuguese, language.Spanish)
+
+			Convey("Then the chain should still terminate", func() {
+				chain := i18n.GetFallbackChain(language.Spanish)
+				So(chain, ShouldHaveLength, 3)
+			})
+		})
+
+		Convey("When falling back to the default language is enabled", func() {
+			So(i18n.SetFallbackToDefault(true), ShouldBeNil)
+
+			Convey("Then every chain should end at the default language", func() {
+				So(i18n.T(language.BrazilianPortuguese, "English"), ShouldEqual, "en")
+				So(i18n.T(language.CanadianFrench, "English"), ShouldEqual, "en")
+			})
+
+			Convey("Then the setting should be persisted in storage", func() {
+				enabled, err := storage.FallbackToDefault()
+				So(err, ShouldBeNil)
+				So(enabled, ShouldBeTrue)
+
+				other := New(storage)
+				So(other.Sync(), ShouldBeNil)
+				So(other.T(language.BrazilianPortuguese, "English"), ShouldEqual, "en")
+			})
+		})
+	})
+
+	Convey("Given a storage that does not store fallback chains", t, func() {
+		i18n := New(struct{ Storage }{NewInMemoryStorage()})
+
+		i18n.SetDefaultLanguage(language.English)
+		i18n.Add(&Translation{Lang: language.Spanish, Key: "Spanish", Value: "es"})
+
+		Convey("When a fallback chain is configured", func() {
+			err := i18n.SetFallback(language.BrazilianPortuguese, language.Spanish)
+			So(err, ShouldBeNil)
+
+			Convey("Then the chain should be kept in memory across syncs", func() {
+				So(i18n.T(language.BrazilianPortuguese, "Spanish"), ShouldEqual, "es")
+				So(i18n.Sync(), ShouldBeNil)
+				So(i18n.T(language.BrazilianPortuguese, "Spanish"), ShouldEqual, "es")
+			})
+		})
+	})
+}
@@ -0,0 +1,85 @@
+package i18n
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Args holds named arguments for placeholder interpolation
+type Args map[string]interface{}
+
+// Format substitutes placeholders in a translation value. Named placeholders
+// such as {name} are taken from Args arguments, positional placeholders such
+// as {0} are taken from the remaining arguments in order. Placeholders with no
+// matching argument are left untouched and unused arguments are ignored.
+// Literal braces are written as {{ and }}.
+func Format(value string, args ...interface{}) string {
+	if !strings.ContainsAny(value, "{}") {
+		return value
+	}
+
+	named := collectArgs(args)
+
+	var buf bytes.Buffer
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+
+		if c == '}' && i+1 < len(value) && value[i+1] == '}' {
+			buf.WriteByte('}')
+			i++
+			continue
+		}
+
+		if c != '{' {
+			buf.WriteByte(c)
+			continue
+		}
+
+		if i+1 < len(value) && value[i+1] == '{' {
+			buf.WriteByte('{')
+			i++
+			continue
+		}
+
+		end := strings.IndexAny(value[i+1:], "{}")
+		if end < 0 || value[i+1+end] != '}' {
+			buf.WriteByte(c)
+			continue
+		}
+
+		name := value[i+1 : i+1+end]
+		if arg, ok := named[name]; ok {
+			fmt.Fprint(&buf, arg)
+		} else {
+			buf.WriteString(value[i : i+end+2])
+		}
+
+		i += end + 1
+	}
+
+	return buf.String()
+}
+
+// collectArgs merges Args arguments and stores the remaining arguments under
+// their position
+func collectArgs(args []interface{}) Args {
+	named := make(Args)
+	position := 0
+
+	for _, arg := range args {
+		if a, ok := arg.(Args); ok {
+			for k, v := range a {
+				named[k] = v
+			}
+			continue
+		}
+
+		named[strconv.Itoa(position)] = arg
+		position++
+	}
+
+	return named
+}
@@ -0,0 +1,48 @@
+package i18n
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a value with placeholders", t, func() {
+		Convey("Then named placeholders should be replaced", func() {
+			result := Format("Hello {name}, you have {count} messages", Args{"name": "Bob", "count": 3})
+			So(result, ShouldEqual, "Hello Bob, you have 3 messages")
+		})
+
+		Convey("Then positional placeholders should be replaced", func() {
+			result := Format("{1} before {0}", "a", "b")
+			So(result, ShouldEqual, "b before a")
+		})
+
+		Convey("Then named and positional placeholders can be mixed", func() {
+			result := Format("{0} {name}", Args{"name": "Bob"}, "Hello")
+			So(result, ShouldEqual, "Hello Bob")
+		})
+
+		Convey("Then placeholders without arguments should be left untouched", func() {
+			result := Format("Hello {name} {0}")
+			So(result, ShouldEqual, "Hello {name} {0}")
+		})
+
+		Convey("Then extra arguments should be ignored", func() {
+			result := Format("Hello {name}", Args{"name": "Bob", "other": "x"}, "y")
+			So(result, ShouldEqual, "Hello Bob")
+		})
+
+		Convey("Then escaped braces should be written literally", func() {
+			result := Format("{{name}} is {name}}}", Args{"name": "Bob"})
+			So(result, ShouldEqual, "{name} is Bob}")
+		})
+
+		Convey("Then unterminated placeholders should be written literally", func() {
+			result := Format("Hello {name", Args{"name": "Bob"})
+			So(result, ShouldEqual, "Hello {name")
+		})
+	})
+}
@@ -2,7 +2,9 @@ package i18n
 
 import (
 	"fmt"
+	"strings"
 
+	"golang.org/x/net/context"
 	"golang.org/x/text/language"
 )
 
@@ -13,7 +15,15 @@ type Group struct {
 }
 
 func (group *Group) key(k string) string {
-	return fmt.Sprintf("%s.%s", group.group, k)
+	return join(group.group, k)
+}
+
+// join adds a key to a group prefix, keys in the root group have no prefix
+func join(prefix string, k string) string {
+	if prefix == "" {
+		return k
+	}
+	return fmt.Sprintf("%s.%s", prefix, k)
 }
 
 // Group gets a translation group
@@ -24,11 +34,51 @@ func (group *Group) Group(key string) *Group {
 	}
 }
 
+// Keys gets the sorted keys in the group and its subgroups, relative to the
+// group
+func (group *Group) Keys() []string {
+	prefix := group.key("")
+	keys := group.i18n.Keys(prefix)
+
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, prefix)
+	}
+
+	return keys
+}
+
+// Children gets the sorted names of the subgroups directly below the group
+func (group *Group) Children() []string {
+	var children []string
+
+	for _, key := range group.Keys() {
+		i := strings.Index(key, ".")
+		if i < 0 {
+			continue
+		}
+
+		child := key[:i]
+		if len(children) == 0 || children[len(children)-1] != child {
+			children = append(children, child)
+		}
+	}
+
+	return children
+}
+
 // GenerateHelper generates a method that allways gets tags in a certain language
 // This is usefull for passing to the template engine
 func (group *Group) GenerateHelper(tag language.Tag) T {
 	return T(func(key string) string {
-		return group.T(tag, group.key(key))
+		return group.T(tag, key)
+	})
+}
+
+// GenerateFormatHelper generates a method that allways gets and formats tags in
+// a certain language, this is usefull for passing to the template engine
+func (group *Group) GenerateFormatHelper(tag language.Tag) Tf {
+	return Tf(func(key string, args ...interface{}) string {
+		return group.Tf(tag, key, args...)
 	})
 }
 
@@ -37,6 +87,38 @@ func (group *Group) T(lang interface{}, key string) string {
 	return group.i18n.T(lang, group.key(key))
 }
 
+// Tf is a helper method to get a translation and substitute its placeholders
+func (group *Group) Tf(lang interface{}, key string, args ...interface{}) string {
+	return group.i18n.Tf(lang, group.key(key), args...)
+}
+
+// Format gets a translation and formats it with the arguments
+func (group *Group) Format(lang interface{}, key string, args ...interface{}) (string, error) {
+	return group.i18n.Format(lang, group.key(key), args...)
+}
+
+// Plural gets the plural variant of a translation for n
+func (group *Group) Plural(lang interface{}, key string, n int) string {
+	return group.i18n.Plural(lang, group.key(key), n)
+}
+
+// TCtx gets a translation in the language stored in the context
+func (group *Group) TCtx(ctx context.Context, key string, args ...interface{}) string {
+	return group.i18n.TCtx(ctx, group.key(key), args...)
+}
+
+// FormatCtx gets a translation in the language stored in the context and
+// formats it
+func (group *Group) FormatCtx(ctx context.Context, key string, args ...interface{}) (string, error) {
+	return group.i18n.FormatCtx(ctx, group.key(key), args...)
+}
+
+// PluralCtx gets the plural variant of a translation for n in the language
+// stored in the context
+func (group *Group) PluralCtx(ctx context.Context, key string, n int) string {
+	return group.i18n.PluralCtx(ctx, group.key(key), n)
+}
+
 // GetWithLangString parses the lang string before lookip up the translation
 func (group *Group) GetWithLangString(lang string, key string) (*Translation, error) {
 	return group.i18n.GetWithLangString(lang, group.key(key))
@@ -47,13 +129,167 @@ func (group *Group) Get(lang language.Tag, key string) *Translation {
 	return group.i18n.Get(lang, group.key(key))
 }
 
+// Lookup gets a translation along with the language that answered
+func (group *Group) Lookup(lang language.Tag, key string) *LookupResult {
+	return group.i18n.Lookup(lang, group.key(key))
+}
+
 // Add translation
 func (group *Group) Add(translation *Translation) error {
-	translation.Key = group.key(translation.Key)
-	return group.i18n.Add(translation)
+	t := *translation
+	t.Key = group.key(translation.Key)
+	return group.i18n.Add(&t)
 }
 
 // Delete translation
 func (group *Group) Delete(translation *Translation) error {
-	return group.i18n.Delete(translation)
+	t := *translation
+	t.Key = group.key(translation.Key)
+	return group.i18n.Delete(&t)
+}
+
+// translations gets every translation in the group and its subgroups,
+// including those in review that the index leaves out. In lazy mode they are
+// read from storage as languages may not be loaded. Writable reads only the
+// writable layer of a LayeredStorage, so translations served from its other
+// layers are not written to it. It must be called with the lock held.
+func (group *Group) translations(writable bool) ([]*Translation, error) {
+	c := group.i18n.load()
+	if c.lazy.enabled || (writable && group.i18n.layered() != nil) {
+		return group.i18n.prefixed(context.Background(), group.key(""), writable)
+	}
+
+	prefix := group.key("")
+
+	var translations []*Translation
+	for _, entries := range c.entries {
+		for key, entry := range entries {
+			if strings.HasPrefix(key, prefix) {
+				translations = append(translations, entry.translation)
+			}
+		}
+	}
+
+	return translations, nil
+}
+
+// rekey copies translations moving them from the group to the prefix
+func (group *Group) rekey(translations []*Translation, prefix string) []*Translation {
+	copies := make([]*Translation, 0, len(translations))
+
+	for _, translation := range translations {
+		t := *translation
+		t.Key = join(prefix, strings.TrimPrefix(translation.Key, group.key("")))
+
+		if translation.Plurals != nil {
+			t.Plurals = make(map[PluralForm]string, len(translation.Plurals))
+			for form, value := range translation.Plurals {
+				t.Plurals[form] = value
+			}
+		}
+
+		t.Metadata = translation.Metadata.copy()
+
+		if translation.Approved != nil {
+			t.Approved = group.rekey([]*Translation{translation.Approved}, prefix)[0]
+		}
+
+		copies = append(copies, &t)
+	}
+
+	return copies
+}
+
+// Export gets the value of every key in the group and its subgroups resolved
+// in the language, following the fallback chain. Keys are relative to the
+// group, keys without a translation in the chain are left out.
+func (group *Group) Export(lang language.Tag) map[string]string {
+	c := group.i18n.loaded(lang)
+	prefix := group.key("")
+
+	values := make(map[string]string)
+	for _, key := range c.index().prefixed(prefix) {
+		if entry, _, _ := c.lookup(lang, key, false); entry != nil {
+			values[strings.TrimPrefix(key, prefix)] = entry.translation.Value
+		}
+	}
+
+	return values
+}
+
+// DeleteAll deletes every translation in the group and its subgroups, in
+// every language, as a single write
+func (group *Group) DeleteAll() error {
+	i18n := group.i18n
+
+	i18n.lock.Lock()
+	defer i18n.lock.Unlock()
+
+	translations, err := group.translations(false)
+	if err != nil || len(translations) == 0 {
+		return err
+	}
+
+	return i18n.deleteMany(context.Background(), "DeleteAll", translations)
+}
+
+// CopyTo copies every translation in the group and its subgroups into
+// another group as a single write, the other group may belong to a different
+// I18n. Existing translations in the other group are overwritten. A copy to a
+// different I18n copies the group as it was when read.
+func (group *Group) CopyTo(other *Group) error {
+	i18n := group.i18n
+
+	if other.i18n != i18n {
+		i18n.lock.Lock()
+		translations, err := group.translations(false)
+		i18n.lock.Unlock()
+
+		if err != nil || len(translations) == 0 {
+			return err
+		}
+
+		return other.i18n.AddMany(group.rekey(translations, other.group))
+	}
+
+	i18n.lock.Lock()
+	defer i18n.lock.Unlock()
+
+	translations, err := group.translations(false)
+	if err != nil || len(translations) == 0 {
+		return err
+	}
+
+	copies := group.rekey(translations, other.group)
+
+	messages, err := parseMany(copies)
+	if err != nil {
+		return err
+	}
+
+	// The copies are only known once the group is read under the lock, so
+	// the versions they replace are read under it too
+	current, err := i18n.unloaded(context.Background(), copies)
+	if err != nil {
+		return err
+	}
+
+	return i18n.addMany(context.Background(), "CopyTo", copies, messages, current)
+}
+
+// MoveTo moves every translation in the group and its subgroups under the
+// prefix as a single write, so checkout.title moved to cart.checkout becomes
+// cart.checkout.title. An empty prefix moves them to the root.
+func (group *Group) MoveTo(prefix string) error {
+	i18n := group.i18n
+
+	i18n.lock.Lock()
+	defer i18n.lock.Unlock()
+
+	translations, err := group.translations(true)
+	if err != nil || len(translations) == 0 {
+		return err
+	}
+
+	return i18n.moveMany(context.Background(), "MoveTo", group.rekey(translations, prefix), translations)
 }
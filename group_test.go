@@ -26,31 +26,45 @@ func TestGroup(t *testing.T) {
 			err := group.Add(expected)
 			So(err, ShouldBeNil)
 
+			stored := *expected
+			stored.Key = "SomeKey.SomeKey"
+
+			Convey("Then the translation given should not be changed", func() {
+				So(expected.Key, ShouldEqual, "SomeKey")
+			})
+
 			Convey("Then the translation should be accessable", func() {
 				result := group.Get(language.English, "SomeKey")
 				So(result, ShouldNotBeNil)
-				So(result, ShouldResemble, expected)
+				So(result, ShouldResemble, &stored)
 			})
 
 			Convey("Then the translation should be accessable with locle string", func() {
 				result, err := group.GetWithLangString("en", "SomeKey")
 				So(err, ShouldBeNil)
 				So(result, ShouldNotBeNil)
-				So(result, ShouldResemble, expected)
+				So(result, ShouldResemble, &stored)
 			})
 
 			Convey("Then the translation should be accessable with a child language", func() {
 				result := group.Get(language.BritishEnglish, "SomeKey")
 				So(err, ShouldBeNil)
 				So(result, ShouldNotBeNil)
-				So(result, ShouldResemble, expected)
+				So(result, ShouldResemble, &stored)
+			})
+
+			Convey("Then a lookup should report the language that answered", func() {
+				result := group.Lookup(language.English, "SomeKey")
+				So(result.Found, ShouldBeTrue)
+				So(result.Key, ShouldEqual, "SomeKey.SomeKey")
+				So(result.Depth, ShouldEqual, 0)
 			})
 
 			Convey("Then the translation should exist in the storage", func() {
 				results, err := storage.GetAll()
 				So(err, ShouldBeNil)
 				So(results, ShouldHaveLength, 1)
-				So(results[0], ShouldResemble, expected)
+				So(results[0], ShouldResemble, &stored)
 			})
 
 			Convey("Then the translation should be accessable through the helper method", func() {
@@ -59,6 +73,58 @@ func TestGroup(t *testing.T) {
 				So(result1, ShouldEqual, expected.Value)
 				So(result2, ShouldEqual, expected.Value)
 			})
+
+			Convey("Then the translation should be accessable through the generated helper", func() {
+				t := group.GenerateHelper(language.English)
+				So(t("SomeKey"), ShouldEqual, expected.Value)
+			})
+
+			Convey("Then the generated helper should apply the group prefix once", func() {
+				i18n.Add(&Translation{
+					Lang:  language.English,
+					Key:   "SomeKey.SomeKey.SomeKey",
+					Value: "DoublePrefixed",
+				})
+
+				t := group.GenerateHelper(language.English)
+				So(t("SomeKey"), ShouldEqual, expected.Value)
+				So(t("Missing"), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When a translation with placeholders is added", func() {
+			err := group.Add(&Translation{
+				Lang:  language.English,
+				Key:   "Greeting",
+				Value: "Hello {0}",
+			})
+			So(err, ShouldBeNil)
+
+			Convey("Then the placeholders should be substituted", func() {
+				So(group.Tf(language.English, "Greeting", "Bob"), ShouldEqual, "Hello Bob")
+			})
+
+			Convey("Then the placeholders should be substituted through the helper method", func() {
+				tf := group.GenerateFormatHelper(language.English)
+				So(tf("Greeting", "Bob"), ShouldEqual, "Hello Bob")
+			})
+		})
+
+		Convey("When a translation with plural variants is added", func() {
+			err := group.Add(&Translation{
+				Lang:  language.English,
+				Key:   "Items",
+				Value: "items",
+				Plurals: map[PluralForm]string{
+					PluralOne: "item",
+				},
+			})
+			So(err, ShouldBeNil)
+
+			Convey("Then the variant should be accessable through the group", func() {
+				So(group.Plural(language.English, "Items", 1), ShouldEqual, "item")
+				So(group.Plural(language.English, "Items", 2), ShouldEqual, "items")
+			})
 		})
 	})
 
@@ -92,4 +158,143 @@ func TestGroup(t *testing.T) {
 			})
 		})
 	})
+
+	Convey("Given a group with subgroups", t, func() {
+		storage := NewInMemoryStorage()
+		i18n := New(storage)
+		i18n.SetFallback(language.BritishEnglish, language.English)
+		i18n.AddMany([]*Translation{
+			{Lang: language.English, Key: "checkout.title", Value: "Checkout"},
+			{Lang: language.English, Key: "checkout.button.pay", Value: "Pay"},
+			{Lang: language.BritishEnglish, Key: "checkout.title", Value: "Check out"},
+			{Lang: language.English, Key: "checkoutOther", Value: "Other"},
+			{Lang: language.English, Key: "cart.title", Value: "Cart"},
+		})
+		group := i18n.Group("checkout")
+
+		Convey("When the group is exported", func() {
+			values := group.Export(language.BritishEnglish)
+
+			Convey("Then every key should be resolved in the language", func() {
+				So(values, ShouldResemble, map[string]string{
+					"title":      "Check out",
+					"button.pay": "Pay",
+				})
+			})
+		})
+
+		Convey("When the group is deleted", func() {
+			So(group.DeleteAll(), ShouldBeNil)
+
+			Convey("Then only the subtree should be removed", func() {
+				So(i18n.Keys(""), ShouldResemble, []string{"cart.title", "checkoutOther"})
+
+				results, err := storage.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 2)
+			})
+		})
+
+		Convey("When the group is copied", func() {
+			So(group.CopyTo(i18n.Group("cart.checkout")), ShouldBeNil)
+
+			Convey("Then both groups should have the translations", func() {
+				So(i18n.T(language.BritishEnglish, "cart.checkout.title"), ShouldEqual, "Check out")
+				So(i18n.T(language.English, "cart.checkout.button.pay"), ShouldEqual, "Pay")
+				So(i18n.T(language.English, "checkout.title"), ShouldEqual, "Checkout")
+			})
+		})
+
+		Convey("When the group is copied to another i18n", func() {
+			other := New()
+			So(group.CopyTo(other.Group("imported")), ShouldBeNil)
+
+			Convey("Then the other i18n should have the translations", func() {
+				So(other.Keys(""), ShouldResemble, []string{"imported.button.pay", "imported.title"})
+			})
+		})
+
+		Convey("When the group is moved", func() {
+			So(group.MoveTo("cart.checkout"), ShouldBeNil)
+
+			Convey("Then the translations should only exist under the new prefix", func() {
+				So(i18n.Keys(""), ShouldResemble, []string{"cart.checkout.button.pay", "cart.checkout.title", "cart.title", "checkoutOther"})
+
+				results, err := storage.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 5)
+			})
+		})
+
+		Convey("When the group is moved and a storage fails", func() {
+			failing := New(storage, &failingStorage{NewInMemoryStorage().(FallbackStorage)})
+			failing.Sync()
+
+			err := failing.Group("checkout").MoveTo("cart.checkout")
+
+			Convey("Then nothing should be moved", func() {
+				So(err, ShouldNotBeNil)
+
+				results, err := storage.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 5)
+				for _, result := range results {
+					So(result.Key, ShouldNotStartWith, "cart.checkout")
+				}
+				So(failing.Keys("cart.checkout"), ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given translations in the root group", t, func() {
+		i18n := New(NewInMemoryStorage())
+		i18n.AddMany([]*Translation{
+			{Lang: language.English, Key: "title", Value: "Title"},
+			{Lang: language.English, Key: "menu.title", Value: "Menu"},
+		})
+		root := i18n.Group("")
+
+		Convey("Then keys should not be prefixed", func() {
+			So(root.T(language.English, "title"), ShouldEqual, "Title")
+			So(root.Keys(), ShouldResemble, []string{"menu.title", "title"})
+		})
+
+		Convey("When a group is moved to the root", func() {
+			So(i18n.Group("menu").MoveTo(""), ShouldBeNil)
+
+			Convey("Then its keys should not be prefixed", func() {
+				So(i18n.Keys(""), ShouldResemble, []string{"title"})
+				So(i18n.T(language.English, "title"), ShouldEqual, "Menu")
+			})
+		})
+	})
+
+	Convey("Given a group in a layered storage", t, func() {
+		base := NewInMemoryStorage()
+		override := NewInMemoryStorage()
+		override.Store(&Translation{Lang: language.English, Key: "checkout.title", Value: "Override"})
+
+		layered := NewLayeredStorage(base)
+		layered.AddLayer("override", override)
+
+		i18n := New(layered)
+		i18n.Add(&Translation{Lang: language.English, Key: "checkout.title", Value: "Checkout"})
+		i18n.Add(&Translation{Lang: language.English, Key: "checkout.pay", Value: "Pay"})
+
+		Convey("When the group is moved", func() {
+			So(i18n.Group("checkout").MoveTo("cart"), ShouldBeNil)
+
+			Convey("Then only the writable layer should be moved", func() {
+				results, err := base.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 2)
+				for _, result := range results {
+					So(result.Value, ShouldNotEqual, "Override")
+				}
+
+				So(i18n.T(language.English, "cart.title"), ShouldEqual, "Checkout")
+				So(i18n.T(language.English, "checkout.title"), ShouldEqual, "Override")
+			})
+		})
+	})
 }
@@ -1,9 +1,12 @@
 package i18n
 
 import (
+	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"golang.org/x/net/context"
 	"golang.org/x/text/language"
 )
 
@@ -12,34 +15,92 @@ type Translation struct {
 	Lang  language.Tag
 	Key   string
 	Value string
+
+	// Plurals holds optional plural variants keyed by CLDR category
+	Plurals map[PluralForm]string
+
+	// ICU marks the value as an ICU MessageFormat pattern
+	ICU bool
+
+	// State is where the translation is in the review workflow, only approved
+	// translations are served unless preview mode is on. Translations without
+	// a state are approved.
+	State State
+
+	// Approved keeps the approved version of a translation while a revision
+	// of it is in review, it is served until the revision is approved
+	Approved *Translation
+
+	// Metadata optionally describes the translation
+	Metadata *Metadata
+}
+
+// LookupResult describes the outcome of a translation lookup
+type LookupResult struct {
+	Translation *Translation
+	Key         string
+	Value       string
+
+	// Requested is the language asked for and Resolved the language the
+	// translation was found in
+	Requested language.Tag
+	Resolved  language.Tag
+
+	// Depth is the position of Resolved in the fallback chain, 0 means the
+	// requested language answered
+	Depth int
+	Found bool
 }
 
 // T is a function for getting a key from the storage
 type T func(string) string
 
-// I18n defines basic translation get/set methods
+// Tf is a function for getting a key from the storage and formatting it
+type Tf func(string, ...interface{}) string
+
+// I18n defines basic translation get/set methods. Reads are served from an
+// immutable catalog without locking, writes are serialized and publish a new
+// catalog once storage has been updated.
 type I18n struct {
-	lock sync.RWMutex
+	lock    sync.Mutex
+	catalog atomic.Value
+
+	storage []Storage
+
+	watching chan struct{}
+	feeds    int
+	echoes   map[string]*echo
 
-	storage      []Storage
-	translations *Cache
+	syncLock   sync.Mutex
+	syncCancel context.CancelFunc
+	syncDone   chan struct{}
 
-	defaultLanguage    language.Tag
-	supportedLanguages []language.Tag
+	loadLock     sync.Mutex
+	loadFailures int
+	loadRetry    time.Time
 
-	quit chan struct{}
+	listenersLock sync.Mutex
+	listeners     map[int]func(*Change)
+	nextListener  int
 }
 
-// New translation manager
+// New translation manager, writes go to every storage. Use a LayeredStorage to
+// give storages an explicit precedence and a single writable layer.
 func New(storage ...Storage) *I18n {
 	if len(storage) == 0 {
 		storage = []Storage{NewInMemoryStorage()}
 	}
 
-	return &I18n{
-		storage:      storage,
-		translations: new(Cache),
+	i18n := &I18n{
+		storage: storage,
 	}
+	i18n.catalog.Store(newCatalog())
+
+	return i18n
+}
+
+func (i18n *I18n) load() *catalog {
+	return i18n.catalog.Load().(*catalog)
 }
 
 // GenerateHelper generates a method that allways gets tags in a certain language
@@ -50,39 +111,42 @@ func (i18n *I18n) GenerateHelper(tag language.Tag) T {
 	})
 }
 
-// AddSupportedLanguage adds a supported language in storage
+// GenerateFormatHelper generates a method that allways gets and formats tags in
+// a certain language, this is usefull for passing to the template engine
+func (i18n *I18n) GenerateFormatHelper(tag language.Tag) Tf {
+	return Tf(func(key string, args ...interface{}) string {
+		return i18n.Tf(tag, key, args...)
+	})
+}
+
+// AddSupportedLanguage adds supported languages in storage as a single write
 func (i18n *I18n) AddSupportedLanguage(tags ...language.Tag) error {
 	i18n.lock.Lock()
 	defer i18n.lock.Unlock()
 
-TagLoop:
-	for _, tag := range tags {
-
-		for _, s := range i18n.storage {
-			err := s.StoreSupportedLanguage(tag)
-			if err != nil {
-				return err
-			}
-		}
+	err := i18n.write(context.Background(), storeSupportedLanguages(tags))
+	if err != nil {
+		return err
+	}
 
-		for _, lang := range i18n.supportedLanguages {
-			if lang.String() == tag.String() {
-				continue TagLoop
-			}
-		}
-		i18n.supportedLanguages = append(i18n.supportedLanguages, tag)
+	next := i18n.load()
+	for _, tag := range tags {
+		next = next.withSupportedLanguage(tag)
 	}
+
+	i18n.catalog.Store(next)
+	i18n.notify(&Change{Type: ChangeLanguages})
 	return nil
 }
 
 // GetSupportedLanguages gets the supported languages from storage
 func (i18n *I18n) GetSupportedLanguages() []language.Tag {
-	return i18n.supportedLanguages
+	return i18n.load().supportedLanguages
 }
 
 // GetDefaultLanguage gets the default language from storage
 func (i18n *I18n) GetDefaultLanguage() language.Tag {
-	return i18n.defaultLanguage
+	return i18n.load().defaultLanguage
 }
 
 // RemoveSupportedLanguage removes a supported language in storage
@@ -90,141 +154,444 @@ func (i18n *I18n) RemoveSupportedLanguage(tag language.Tag) error {
 	i18n.lock.Lock()
 	defer i18n.lock.Unlock()
 
-	for _, s := range i18n.storage {
-		err := s.DeleteSupportedLanguage(tag)
-		if err != nil {
-			return err
-		}
+	err := i18n.write(context.Background(), deleteSupportedLanguage(tag))
+	if err != nil {
+		return err
 	}
 
-	for i, lang := range i18n.supportedLanguages {
-		if lang.String() == tag.String() {
-			i18n.supportedLanguages = append(i18n.supportedLanguages[:i], i18n.supportedLanguages[i+1:]...)
-		}
+	i18n.catalog.Store(i18n.load().withoutSupportedLanguage(tag))
+	i18n.notify(&Change{Type: ChangeLanguages})
+
+	return nil
+}
+
+// SetFallback sets the languages tried, in order, when a translation is missing
+// in a language and its parents. The chain is persisted in storage.
+func (i18n *I18n) SetFallback(tag language.Tag, chain ...language.Tag) error {
+	i18n.lock.Lock()
+	defer i18n.lock.Unlock()
+
+	fallback := &Fallback{
+		Lang:  tag,
+		Chain: append([]language.Tag(nil), chain...),
+	}
+
+	err := i18n.write(context.Background(), storeFallback(fallback))
+	if err != nil {
+		return err
 	}
 
+	i18n.catalog.Store(i18n.load().withFallback(tag, fallback.Chain))
+	i18n.notify(&Change{Type: ChangeLanguages})
+
 	return nil
 }
 
-// SetDefaultLanguage sets the default language in storage
-func (i18n *I18n) SetDefaultLanguage(tag language.Tag) error {
-	i18n.AddSupportedLanguage(tag)
+// RemoveFallback removes the fallback chain of a language in storage
+func (i18n *I18n) RemoveFallback(tag language.Tag) error {
+	i18n.lock.Lock()
+	defer i18n.lock.Unlock()
+
+	err := i18n.write(context.Background(), deleteFallback(tag))
+	if err != nil {
+		return err
+	}
 
+	i18n.catalog.Store(i18n.load().withoutFallback(tag))
+	i18n.notify(&Change{Type: ChangeLanguages})
+
+	return nil
+}
+
+// GetFallback gets the fallback chain configured for a language
+func (i18n *I18n) GetFallback(tag language.Tag) []language.Tag {
+	return append([]language.Tag(nil), i18n.load().fallbacks[tag.String()]...)
+}
+
+// GetFallbackChain gets every language, in order, that a translation is looked
+// up in when requested in tag
+func (i18n *I18n) GetFallbackChain(tag language.Tag) []language.Tag {
+	return i18n.load().chain(tag)
+}
+
+// SetFallbackToDefault sets whether every fallback chain ends at the default
+// language before the root language. The setting is persisted in storage.
+func (i18n *I18n) SetFallbackToDefault(enabled bool) error {
 	i18n.lock.Lock()
 	defer i18n.lock.Unlock()
 
-	for _, s := range i18n.storage {
-		err := s.SetDefaultLanguage(tag)
-		if err != nil {
-			return err
-		}
+	err := i18n.write(context.Background(), setFallbackToDefault(enabled))
+	if err != nil {
+		return err
 	}
 
-	i18n.defaultLanguage = tag
+	next := i18n.load().clone()
+	next.fallbackToDefault = enabled
+	i18n.catalog.Store(next)
+	i18n.notify(&Change{Type: ChangeLanguages})
 
 	return nil
 }
 
-// Sync translations with database
-func (i18n *I18n) Sync() error {
+// SetDefaultLanguage sets the default language in storage, adding it to the
+// supported languages in the same write
+func (i18n *I18n) SetDefaultLanguage(tag language.Tag) error {
 	i18n.lock.Lock()
 	defer i18n.lock.Unlock()
 
-	i18n.translations.Clear()
+	err := i18n.write(context.Background(), setDefaultLanguage(tag))
+	if err != nil {
+		return err
+	}
 
-	for _, storage := range i18n.storage {
-		translations, err := storage.GetAll()
+	next := i18n.load().withSupportedLanguage(tag).clone()
+	next.defaultLanguage = tag
+	i18n.catalog.Store(next)
+	i18n.notify(&Change{Type: ChangeLanguages})
+
+	return nil
+}
+
+// Sync translations with database, ICU translations that can not be parsed
+// are skipped and the first syntax error is returned once the sync completes.
+// The catalog is still replaced in that case, so a Syncer counts the sync as
+// successful and observers are sent the number skipped.
+// The new catalog is built off to the side and published in one step so reads
+// never see a partial state.
+func (i18n *I18n) Sync() error {
+	return i18n.SyncContext(context.Background())
+}
+
+// SyncContext syncs translations as Sync, the catalog is left unchanged if the
+// context is done before the sync completes
+func (i18n *I18n) SyncContext(ctx context.Context) (err error) {
+	i18n.lock.Lock()
+	defer i18n.lock.Unlock()
 
+	start := time.Now()
+	loaded := 0
+	skipped := 0
+	published := false
+
+	current := i18n.load()
+	logger := i18n.logger()
+
+	logger.Debug("sync started", "storages", len(i18n.storage))
+
+	// A syntax error is returned once the catalog is published, so it is
+	// reported as skipped translations rather than a failed sync
+	defer func() {
+		if !published {
+			logger.Error("sync failed", "duration", time.Since(start), "error", err)
+			i18n.observeSync(ctx, start, 0, 0, err)
+		} else {
+			logger.Debug("sync finished", "duration", time.Since(start), "translations", loaded, "skipped", skipped)
+			i18n.observeSync(ctx, start, loaded, skipped, nil)
+		}
+	}()
+
+	next := newCatalog()
+	next.fallbacks = current.fallbacks
+	next.fallbackToDefault = current.fallbackToDefault
+	next.missingKeyHandler = current.missingKeyHandler
+	next.observer = current.observer
+	next.logger = current.logger
+	next.lazy = current.lazy
+	next.usage = current.usage
+
+	var syntaxErr error
+	count := 0
+
+	if current.lazy.enabled {
+		count, err = i18n.syncLazy(ctx, current, next)
 		if err != nil {
 			return err
 		}
+	} else {
+		for _, storage := range i18n.storage {
+			translations, err := WithContext(storage).GetAllContext(ctx)
+
+			if err != nil {
+				return err
+			}
+
+			for _, translation := range translations {
+				message, err := translation.message()
+				if err != nil {
+					logger.Warn("skipping translation that can not be parsed", "lang", translation.Lang.String(), "key", translation.Key, "error", err)
+					if syntaxErr == nil {
+						syntaxErr = err
+					}
+					skipped++
+					continue
+				}
 
-		for _, translation := range translations {
-			i18n.translations.Add(translation)
+				next.put(translation, message)
+				count++
+			}
 		}
 	}
 
+	if err := i18n.loadLanguages(ctx, next); err != nil {
+		return err
+	}
+
+	i18n.catalog.Store(next)
+	i18n.notify(&Change{Type: ChangeReload})
+	loaded = count
+	published = true
+
+	return syntaxErr
+}
+
+// loadLanguages loads the supported languages, fallback chains and default
+// language from storage into an unpublished catalog. Fallback settings are
+// kept as they are if no storage implements FallbackStorage.
+func (i18n *I18n) loadLanguages(ctx context.Context, next *catalog) error {
+	next.supportedLanguages = nil
+
 	for _, s := range i18n.storage {
-		tags, err := s.SupportedLanguages()
+		tags, err := WithContext(s).SupportedLanguagesContext(ctx)
 		if err != nil {
 			return err
 		}
 
 	TagLoop:
 		for _, tag := range tags {
-			for _, lang := range i18n.supportedLanguages {
+			for _, lang := range next.supportedLanguages {
 				if lang.String() == tag.String() {
 					continue TagLoop
 				}
 			}
-			i18n.supportedLanguages = append(i18n.supportedLanguages, tag)
+			next.supportedLanguages = append(next.supportedLanguages, tag)
 		}
 	}
 
-	if len(i18n.storage) > 0 {
-		def, err := i18n.storage[0].DefaultLanguage()
+	var fallbacks map[string][]language.Tag
+
+	for _, s := range i18n.storage {
+		fs, ok := WithFallbackContext(s)
+		if !ok {
+			continue
+		}
+
+		// The first storage with fallbacks decides whether chains end at the
+		// default language, as the first storage decides the default language
+		if fallbacks == nil {
+			enabled, err := fs.FallbackToDefaultContext(ctx)
+			if err != nil {
+				return err
+			}
+			next.fallbackToDefault = enabled
+			fallbacks = make(map[string][]language.Tag)
+		}
+
+		results, err := fs.FallbacksContext(ctx)
 		if err != nil {
 			return err
 		}
-		i18n.defaultLanguage = def
+
+		for _, fallback := range results {
+			if _, ok := fallbacks[fallback.Lang.String()]; !ok {
+				fallbacks[fallback.Lang.String()] = append([]language.Tag(nil), fallback.Chain...)
+			}
+		}
+	}
+
+	if fallbacks != nil {
+		next.fallbacks = fallbacks
 	}
+
+	if len(i18n.storage) > 0 {
+		def, err := WithContext(i18n.storage[0]).DefaultLanguageContext(ctx)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		next.defaultLanguage = def
+	}
+
 	return nil
 }
 
-// SetRefreshInterval sets the inerval to sync the translations, 0 means no sync
+// SetRefreshInterval sets the inerval to sync the translations, 0 means no sync.
+// Syncing is done by a Syncer, use one directly for readiness and error
+// reporting. The first sync starts immediately rather than after the interval.
+// Changing the interval, and Close, wait for a sync in progress to finish.
 func (i18n *I18n) SetRefreshInterval(d time.Duration) {
-	i18n.lock.Lock()
-	defer i18n.lock.Unlock()
+	i18n.syncLock.Lock()
+	defer i18n.syncLock.Unlock()
 
-	if i18n.quit != nil {
-		close(i18n.quit)
-		i18n.quit = nil
-	}
+	i18n.stopSyncer()
 
 	if d <= 0 {
 		return
 	}
 
-	refresh := time.NewTicker(d)
-	i18n.quit = make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
 
-	go func(refresh *time.Ticker, quit chan struct{}) {
-		for {
-			select {
-			case <-refresh.C:
-				i18n.Sync()
-			case <-quit:
-				refresh.Stop()
-				return
-			}
-		}
-	}(refresh, i18n.quit)
+	go func(syncer *Syncer) {
+		defer close(done)
+		syncer.Run(ctx)
+	}(NewSyncer(i18n, d))
+
+	i18n.syncCancel = cancel
+	i18n.syncDone = done
 }
 
-// T is a helper method to get translation by lang string or language tag
-func (i18n *I18n) T(lang interface{}, key string) string {
-	var translation *Translation
+// stopSyncer stops the refresh syncer and waits for it to finish, the sync
+// lock must be held
+func (i18n *I18n) stopSyncer() {
+	if i18n.syncCancel == nil {
+		return
+	}
+
+	i18n.syncCancel()
+	<-i18n.syncDone
 
+	i18n.syncCancel = nil
+	i18n.syncDone = nil
+}
+
+func (i18n *I18n) get(ctx context.Context, lang interface{}, key string) *cacheEntry {
 	switch lang.(type) {
 	case string:
-		translation, _ = i18n.GetWithLangString(lang.(string), key)
+		tag, err := language.Parse(lang.(string))
+		if err == nil {
+			return i18n.lookup(ctx, tag, key)
+		}
 	case language.Tag:
-		translation = i18n.Get(lang.(language.Tag), key)
+		return i18n.lookup(ctx, lang.(language.Tag), key)
 	}
 
-	if translation != nil {
-		return translation.Value
+	return nil
+}
+
+// lookup resolves a translation, reporting the result to the observer if one
+// is set
+func (i18n *I18n) lookup(ctx context.Context, lang language.Tag, key string) *cacheEntry {
+	c := i18n.loaded(lang)
+
+	entry, resolved, depth := c.lookup(lang, key, preview(ctx))
+	if c.observer != nil {
+		c.observer.ObserveLookup(ctx, newLookupResult(lang, key, entry, resolved, depth))
+	}
+
+	return entry
+}
+
+// T is a helper method to get translation by lang string or language tag, the
+// missing key handler is used if there is no translation
+func (i18n *I18n) T(lang interface{}, key string) string {
+	if entry := i18n.get(context.Background(), lang, key); entry != nil {
+		return entry.translation.Value
+	}
+
+	return i18n.missing(context.Background(), lang, key)
+}
+
+// Tf is a helper method to get a translation and substitute its placeholders,
+// see Format for the placeholder syntax. ICU translations are evaluated as in
+// I18n.Format and the raw value is returned if that fails.
+func (i18n *I18n) Tf(lang interface{}, key string, args ...interface{}) string {
+	entry := i18n.get(context.Background(), lang, key)
+	if entry == nil {
+		return i18n.missing(context.Background(), lang, key)
+	}
+
+	value, err := entry.format(args)
+	if err != nil {
+		return entry.translation.Value
 	}
 
-	return ""
+	return value
+}
+
+// Format gets a translation and formats it with the arguments. ICU
+// translations are evaluated as MessageFormat using the rules of the language
+// the translation was found in, other translations have their placeholders
+// substituted as in Tf. The missing key handler is used if there is no
+// translation.
+func (i18n *I18n) Format(lang interface{}, key string, args ...interface{}) (string, error) {
+	entry := i18n.get(context.Background(), lang, key)
+	if entry == nil {
+		return i18n.missing(context.Background(), lang, key), nil
+	}
+
+	return entry.format(args)
+}
+
+// Plural gets the plural variant of a translation for n using the CLDR rules
+// of the language the translation was found in
+func (i18n *I18n) Plural(lang interface{}, key string, n int) string {
+	if entry := i18n.get(context.Background(), lang, key); entry != nil {
+		return entry.translation.Plural(n)
+	}
+
+	return i18n.missing(context.Background(), lang, key)
+}
+
+// TCtx gets a translation in the language stored in the context and
+// substitutes its placeholders as in Tf. Options stored in the context apply
+// to the lookup.
+func (i18n *I18n) TCtx(ctx context.Context, key string, args ...interface{}) string {
+	lang := GetLanguageFromContext(ctx)
+
+	entry := i18n.get(ctx, lang, key)
+	if entry == nil {
+		return i18n.missing(ctx, lang, key)
+	}
+
+	value, err := entry.format(args)
+	if err != nil {
+		return entry.translation.Value
+	}
+
+	return value
+}
+
+// FormatCtx gets a translation in the language stored in the context and
+// formats it as in Format
+func (i18n *I18n) FormatCtx(ctx context.Context, key string, args ...interface{}) (string, error) {
+	lang := GetLanguageFromContext(ctx)
+
+	entry := i18n.get(ctx, lang, key)
+	if entry == nil {
+		return i18n.missing(ctx, lang, key), nil
+	}
+
+	return entry.format(args)
+}
+
+// PluralCtx gets the plural variant of a translation for n in the language
+// stored in the context
+func (i18n *I18n) PluralCtx(ctx context.Context, key string, n int) string {
+	lang := GetLanguageFromContext(ctx)
+
+	if entry := i18n.get(ctx, lang, key); entry != nil {
+		return entry.translation.Plural(n)
+	}
+
+	return i18n.missing(ctx, lang, key)
 }
 
 // Close must be called before going out of scope to stop the refresh goroutine
+// and watching storages, it waits for a sync in progress to finish
 func (i18n *I18n) Close() error {
-	if i18n.quit != nil {
-		close(i18n.quit)
-		i18n.quit = nil
+	i18n.syncLock.Lock()
+	i18n.stopSyncer()
+	i18n.syncLock.Unlock()
+
+	i18n.lock.Lock()
+	defer i18n.lock.Unlock()
+
+	if i18n.watching != nil {
+		close(i18n.watching)
+		i18n.watching = nil
+		i18n.feeds = 0
+		i18n.echoes = nil
 	}
+
 	return nil
 }
 
@@ -236,9 +603,11 @@ func (i18n *I18n) Group(key string) *Group {
 	}
 }
 
-// GetWithLangString parses the lang string before lookip up the translation
+// GetWithLangString parses the lang string before lookip up the translation,
+// ErrInvalidLanguage is returned if it can not be parsed. A missing translation
+// is returned as nil without an error, as with Get.
 func (i18n *I18n) GetWithLangString(lang string, key string) (*Translation, error) {
-	tag, err := language.Parse(lang)
+	tag, err := ParseLanguage(lang)
 
 	if err != nil {
 		return nil, err
@@ -247,53 +616,98 @@ func (i18n *I18n) GetWithLangString(lang string, key string) (*Translation, erro
 	return i18n.Get(tag, key), nil
 }
 
-// Get translation
+// Get translation, only approved translations are returned
 func (i18n *I18n) Get(lang language.Tag, key string) *Translation {
-	i18n.lock.RLock()
-	defer i18n.lock.RUnlock()
-
-	for {
-		if t := i18n.translations.Get(lang, key); t != nil {
-			return t
-		}
+	return i18n.GetContext(context.Background(), lang, key)
+}
 
-		if lang.IsRoot() {
-			break
-		}
-		lang = lang.Parent()
+// GetContext gets a translation as Get, drafts and translations waiting for
+// review are returned too if the context is in preview mode
+func (i18n *I18n) GetContext(ctx context.Context, lang language.Tag, key string) *Translation {
+	if entry := i18n.lookup(ctx, lang, key); entry != nil {
+		return entry.translation
 	}
 
 	return nil
 }
 
-// Add translation
+// Lookup gets a translation along with the language that answered and how far
+// down the fallback chain it was found
+func (i18n *I18n) Lookup(lang language.Tag, key string) *LookupResult {
+	return i18n.LookupContext(context.Background(), lang, key)
+}
+
+// LookupContext looks up a translation as Lookup, drafts and translations
+// waiting for review are found too if the context is in preview mode
+func (i18n *I18n) LookupContext(ctx context.Context, lang language.Tag, key string) *LookupResult {
+	c := i18n.loaded(lang)
+
+	entry, resolved, depth := c.lookup(lang, key, preview(ctx))
+	result := newLookupResult(lang, key, entry, resolved, depth)
+
+	if c.observer != nil {
+		c.observer.ObserveLookup(ctx, result)
+	}
+
+	return result
+}
+
+// Add translation, ICU translations are parsed first and a SyntaxError is
+// returned if they are invalid. Read only storages are skipped, a WriteError is
+// returned if no storage accepts the translation.
 func (i18n *I18n) Add(translation *Translation) error {
+	return i18n.AddContext(context.Background(), translation)
+}
+
+// AddContext adds a translation as Add, storages are not written once the
+// context is done
+func (i18n *I18n) AddContext(ctx context.Context, translation *Translation) error {
+	if err := translation.validate(); err != nil {
+		return err
+	}
+
+	message, err := translation.message()
+	if err != nil {
+		return err
+	}
+
+	current, err := i18n.unloaded(ctx, []*Translation{translation})
+	if err != nil {
+		return err
+	}
+
 	i18n.lock.Lock()
 	defer i18n.lock.Unlock()
 
-	for _, storage := range i18n.storage {
-		if err := storage.Store(translation); err != nil {
-			return err
-		}
+	stamped := i18n.stamp([]*Translation{translation}, current, time.Now())
+
+	err = i18n.write(ctx, storeTranslations("Add", stamped))
+	if err != nil {
+		return err
 	}
 
-	i18n.translations.Add(translation)
+	i18n.commit(stamped, []*Message{message}, nil)
 
 	return nil
 }
 
-// Delete translation
+// Delete translation, read only storages are skipped
 func (i18n *I18n) Delete(translation *Translation) error {
+	return i18n.DeleteContext(context.Background(), translation)
+}
+
+// DeleteContext deletes a translation as Delete, storages are not written once
+// the context is done
+func (i18n *I18n) DeleteContext(ctx context.Context, translation *Translation) error {
 	i18n.lock.Lock()
 	defer i18n.lock.Unlock()
 
-	for _, storage := range i18n.storage {
-		if err := storage.Delete(translation); err != nil {
-			return err
-		}
+	err := i18n.write(ctx, deleteTranslations("Delete", []*Translation{translation}))
+	if err != nil {
+		return err
 	}
 
-	i18n.translations.Delete(translation)
+	i18n.commit(nil, nil, []*Translation{translation})
 
 	return nil
 }
@@ -46,6 +46,23 @@ func TestI18n(t *testing.T) {
 				So(result, ShouldResemble, expected)
 			})
 
+			Convey("Then a lookup should report the language that answered", func() {
+				result := i18n.Lookup(language.BritishEnglish, "SomeKey")
+				So(result.Found, ShouldBeTrue)
+				So(result.Value, ShouldEqual, expected.Value)
+				So(result.Translation, ShouldResemble, expected)
+				So(result.Requested, ShouldResemble, language.BritishEnglish)
+				So(result.Resolved, ShouldResemble, language.English)
+				So(result.Depth, ShouldEqual, 2)
+			})
+
+			Convey("Then a lookup of a missing key should not be found", func() {
+				result := i18n.Lookup(language.English, "OtherKey")
+				So(result.Found, ShouldBeFalse)
+				So(result.Value, ShouldEqual, "")
+				So(result.Translation, ShouldBeNil)
+			})
+
 			Convey("Then the translation should exist in the storage", func() {
 				results, err := storage.GetAll()
 				So(err, ShouldBeNil)
@@ -101,6 +118,113 @@ func TestI18n(t *testing.T) {
 			})
 		})
 
+		Convey("When a translation with placeholders is added", func() {
+			err := i18n.Add(&Translation{
+				Lang:  language.English,
+				Key:   "Greeting",
+				Value: "Hello {name}",
+			})
+			So(err, ShouldBeNil)
+
+			Convey("Then the placeholders should be substituted", func() {
+				So(i18n.Tf(language.English, "Greeting", Args{"name": "Bob"}), ShouldEqual, "Hello Bob")
+			})
+
+			Convey("Then the placeholders should be substituted through the helper method", func() {
+				tf := i18n.GenerateFormatHelper(language.English)
+				So(tf("Greeting", Args{"name": "Bob"}), ShouldEqual, "Hello Bob")
+			})
+		})
+
+		Convey("When an ICU translation is added", func() {
+			err := i18n.Add(&Translation{
+				Lang:  language.English,
+				Key:   "Files",
+				Value: "{count, plural, one {# file} other {# files}}",
+				ICU:   true,
+			})
+			So(err, ShouldBeNil)
+
+			Convey("Then it should be formatted with the arguments", func() {
+				result, err := i18n.Format(language.BritishEnglish, "Files", Args{"count": 2})
+				So(err, ShouldBeNil)
+				So(result, ShouldEqual, "2 files")
+			})
+
+			Convey("Then a missing argument should return an error", func() {
+				_, err := i18n.Format(language.English, "Files")
+				So(err, ShouldNotBeNil)
+			})
+
+			Convey("Then it should be formatted through the helper method", func() {
+				So(i18n.Tf(language.English, "Files", Args{"count": 1}), ShouldEqual, "1 file")
+			})
+		})
+
+		Convey("When an invalid ICU translation is added", func() {
+			err := i18n.Add(&Translation{
+				Lang:  language.English,
+				Key:   "Files",
+				Value: "{count, plural, one {# file}",
+				ICU:   true,
+			})
+
+			Convey("Then a syntax error should be returned", func() {
+				So(err, ShouldHaveSameTypeAs, &SyntaxError{})
+			})
+
+			Convey("Then it should not be stored", func() {
+				results, err := storage.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("When an invalid ICU translation is added to the storage", func() {
+			storage.Store(&Translation{
+				Lang:  language.English,
+				Key:   "Invalid",
+				Value: "{count, plural, one {# file}",
+				ICU:   true,
+			})
+			storage.Store(&Translation{
+				Lang:  language.English,
+				Key:   "Valid",
+				Value: "SomeValue",
+			})
+
+			Convey("Then a call to sync should return a syntax error and load the valid translations", func() {
+				err := i18n.Sync()
+				So(err, ShouldHaveSameTypeAs, &SyntaxError{})
+				So(i18n.Get(language.English, "Invalid"), ShouldBeNil)
+				So(i18n.T(language.English, "Valid"), ShouldEqual, "SomeValue")
+			})
+		})
+
+		Convey("When a translation with plural variants is added", func() {
+			err := i18n.Add(&Translation{
+				Lang:  language.Polish,
+				Key:   "Files",
+				Value: "plików",
+				Plurals: map[PluralForm]string{
+					PluralOne:  "plik",
+					PluralFew:  "pliki",
+					PluralMany: "plików",
+				},
+			})
+			So(err, ShouldBeNil)
+
+			Convey("Then the variant should be chosen using the plural rules of the language", func() {
+				So(i18n.Plural(language.Polish, "Files", 1), ShouldEqual, "plik")
+				So(i18n.Plural(language.Polish, "Files", 3), ShouldEqual, "pliki")
+				So(i18n.Plural("pl-PL", "Files", 5), ShouldEqual, "plików")
+			})
+
+			Convey("Then a missing key should return an empty string", func() {
+				So(i18n.Plural(language.Polish, "OtherKey", 1), ShouldEqual, "")
+			})
+		})
+
 		Reset(func() {
 			i18n.Close()
 		})
@@ -0,0 +1,192 @@
+package i18n
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+)
+
+// Search match scores, lower is a closer match
+const (
+	matchKey = iota
+	matchKeySubstring
+	matchValueSubstring
+	matchKeyFuzzy
+	matchValueFuzzy
+)
+
+// keyIndex lists the contents of a catalog that are served outside preview
+// mode, it is built the first time it is needed so writes do not pay for it.
+// Every write publishes a new catalog, so the first listing or search after a
+// write rebuilds the whole index and costs as much as sorting every
+// translation.
+type keyIndex struct {
+	once sync.Once
+
+	keys      []string
+	languages []language.Tag
+	entries   []indexEntry
+}
+
+// indexEntry is a translation with its key and value folded for searching
+type indexEntry struct {
+	translation *Translation
+	key         string
+	value       string
+}
+
+// SearchResult is a translation matched by a search, results with a lower
+// Score are closer matches
+type SearchResult struct {
+	Translation *Translation
+	Score       int
+}
+
+func (c *catalog) index() *keyIndex {
+	c.keys.once.Do(func() {
+		c.keys.build(c)
+	})
+	return c.keys
+}
+
+func (index *keyIndex) build(c *catalog) {
+	seen := make(map[string]bool)
+
+	for _, entries := range c.entries {
+		if len(entries) == 0 {
+			continue
+		}
+
+		lang := false
+		for key, entry := range entries {
+			entry = entry.serve(false)
+			if entry == nil {
+				continue
+			}
+
+			if !lang {
+				index.languages = append(index.languages, entry.translation.Lang)
+				lang = true
+			}
+
+			if !seen[key] {
+				seen[key] = true
+				index.keys = append(index.keys, key)
+			}
+
+			index.entries = append(index.entries, indexEntry{
+				translation: entry.translation,
+				key:         strings.ToLower(key),
+				value:       strings.ToLower(entry.translation.Value),
+			})
+		}
+	}
+
+	sort.Strings(index.keys)
+
+	sort.Slice(index.languages, func(i, j int) bool {
+		return index.languages[i].String() < index.languages[j].String()
+	})
+
+	sort.Slice(index.entries, func(i, j int) bool {
+		a, b := index.entries[i].translation, index.entries[j].translation
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.Lang.String() < b.Lang.String()
+	})
+}
+
+// prefixed gets the sorted keys starting with the prefix
+func (index *keyIndex) prefixed(prefix string) []string {
+	i := sort.SearchStrings(index.keys, prefix)
+
+	var keys []string
+	for ; i < len(index.keys) && strings.HasPrefix(index.keys[i], prefix); i++ {
+		keys = append(keys, index.keys[i])
+	}
+
+	return keys
+}
+
+// Keys gets the sorted keys with a translation in any language that start with
+// the prefix, an empty prefix gets every key
+func (i18n *I18n) Keys(prefix string) []string {
+	return i18n.load().index().prefixed(prefix)
+}
+
+// Languages gets the languages that have translations, unlike
+// GetSupportedLanguages this includes languages that are not supported
+func (i18n *I18n) Languages() []language.Tag {
+	languages := i18n.load().index().languages
+	return append([]language.Tag(nil), languages...)
+}
+
+// Search finds translations whose key or value contains the query, ignoring
+// case. Keys and values that contain the letters of the query in order are
+// matched too, with a higher score. Only translations in the languages given
+// are searched, or every language if there are none.
+func (i18n *I18n) Search(query string, langs ...language.Tag) []*SearchResult {
+	query = strings.ToLower(query)
+
+	filter := make(map[string]bool, len(langs))
+	for _, lang := range langs {
+		filter[lang.String()] = true
+	}
+
+	var results []*SearchResult
+
+	for _, entry := range i18n.load().index().entries {
+		if len(filter) > 0 && !filter[entry.translation.Lang.String()] {
+			continue
+		}
+
+		if score, ok := entry.match(query); ok {
+			results = append(results, &SearchResult{
+				Translation: entry.translation,
+				Score:       score,
+			})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score < results[j].Score
+	})
+
+	return results
+}
+
+func (entry *indexEntry) match(query string) (int, bool) {
+	switch {
+	case entry.key == query:
+		return matchKey, true
+	case strings.Contains(entry.key, query):
+		return matchKeySubstring, true
+	case strings.Contains(entry.value, query):
+		return matchValueSubstring, true
+	case subsequence(entry.key, query):
+		return matchKeyFuzzy, true
+	case subsequence(entry.value, query):
+		return matchValueFuzzy, true
+	}
+
+	return 0, false
+}
+
+// subsequence checks whether the runes of sub appear in s in order
+func subsequence(s string, sub string) bool {
+	for _, r := range s {
+		if sub == "" {
+			return true
+		}
+
+		if first, size := utf8.DecodeRuneInString(sub); r == first {
+			sub = sub[size:]
+		}
+	}
+
+	return sub == ""
+}
@@ -0,0 +1,91 @@
+package i18n
+
+import (
+	"testing"
+
+	"golang.org/x/text/language"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIndex(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a populated i18n", t, func() {
+		i18n := New()
+		i18n.AddMany([]*Translation{
+			{Lang: language.English, Key: "Home.Title", Value: "Welcome home"},
+			{Lang: language.English, Key: "Home.Menu.Open", Value: "Open"},
+			{Lang: language.English, Key: "Home.Menu.Close", Value: "Close"},
+			{Lang: language.Spanish, Key: "Home.Title", Value: "Bienvenido"},
+			{Lang: language.French, Key: "Account.Title", Value: "Compte"},
+		})
+
+		Convey("When keys are listed by prefix", func() {
+			keys := i18n.Keys("Home.")
+
+			Convey("Then the matching keys should be returned sorted", func() {
+				So(keys, ShouldResemble, []string{"Home.Menu.Close", "Home.Menu.Open", "Home.Title"})
+				So(i18n.Keys(""), ShouldHaveLength, 4)
+				So(i18n.Keys("Missing"), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When the languages are listed", func() {
+			languages := i18n.Languages()
+
+			Convey("Then every language with translations should be returned", func() {
+				So(languages, ShouldResemble, []language.Tag{language.English, language.Spanish, language.French})
+			})
+		})
+
+		Convey("When a translation is deleted", func() {
+			i18n.Keys("")
+			i18n.Delete(&Translation{Lang: language.French, Key: "Account.Title"})
+
+			Convey("Then the index should be updated", func() {
+				So(i18n.Keys("Account."), ShouldBeEmpty)
+				So(i18n.Languages(), ShouldHaveLength, 2)
+			})
+		})
+
+		Convey("When the group is browsed", func() {
+			group := i18n.Group("Home")
+
+			Convey("Then its keys and children should be relative to it", func() {
+				So(group.Keys(), ShouldResemble, []string{"Menu.Close", "Menu.Open", "Title"})
+				So(group.Children(), ShouldResemble, []string{"Menu"})
+				So(group.Group("Menu").Children(), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When translations are searched", func() {
+			results := i18n.Search("title")
+
+			Convey("Then keys containing the query should match", func() {
+				So(results, ShouldHaveLength, 3)
+				So(results[0].Translation.Key, ShouldEqual, "Account.Title")
+				So(results[0].Score, ShouldEqual, results[2].Score)
+			})
+
+			Convey("Then values and fuzzy matches should score lower", func() {
+				results := i18n.Search("open")
+				So(results[0].Translation.Key, ShouldEqual, "Home.Menu.Open")
+
+				results = i18n.Search("welcome")
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Translation.Value, ShouldEqual, "Welcome home")
+
+				results = i18n.Search("hmtitle")
+				So(results, ShouldHaveLength, 2)
+				So(results[0].Score, ShouldBeGreaterThan, matchValueSubstring)
+			})
+
+			Convey("Then results should be limited to the languages given", func() {
+				results := i18n.Search("title", language.Spanish)
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Translation.Value, ShouldEqual, "Bienvenido")
+			})
+		})
+	})
+}
@@ -0,0 +1,160 @@
+// Package codec holds the JSON form of translations and changes shared by the
+// redis and server storages, so both encode them the same way
+package codec
+
+import (
+	"time"
+
+	"golang.org/x/text/language"
+
+	"github.com/ThatsMrTalbot/i18n"
+)
+
+// Translation is the JSON form of an i18n.Translation
+type Translation struct {
+	Lang    string                     `json:"lang"`
+	Key     string                     `json:"key"`
+	Value   string                     `json:"value"`
+	Plurals map[i18n.PluralForm]string `json:"plurals,omitempty"`
+	ICU     bool                       `json:"icu,omitempty"`
+
+	State    i18n.State   `json:"state,omitempty"`
+	Approved *Translation `json:"approved,omitempty"`
+	Metadata *Metadata    `json:"metadata,omitempty"`
+}
+
+// Metadata is the JSON form of i18n.Metadata, unset times are left out
+type Metadata struct {
+	Description string     `json:"description,omitempty"`
+	Context     string     `json:"context,omitempty"`
+	MaxLength   int        `json:"max_length,omitempty"`
+	Labels      []string   `json:"labels,omitempty"`
+	Created     *time.Time `json:"created,omitempty"`
+	Updated     *time.Time `json:"updated,omitempty"`
+	Author      string     `json:"author,omitempty"`
+	ReviewedBy  string     `json:"reviewed_by,omitempty"`
+	Reviewed    *time.Time `json:"reviewed,omitempty"`
+}
+
+// Change is the JSON form of an i18n.Change
+type Change struct {
+	Type        i18n.ChangeType `json:"type"`
+	Translation *Translation    `json:"translation,omitempty"`
+}
+
+// EncodeTranslation converts a translation to its JSON form
+func EncodeTranslation(t *i18n.Translation) *Translation {
+	obj := &Translation{
+		Lang:    t.Lang.String(),
+		Key:     t.Key,
+		Value:   t.Value,
+		Plurals: t.Plurals,
+		ICU:     t.ICU,
+
+		State:    t.State,
+		Metadata: encodeMetadata(t.Metadata),
+	}
+
+	if t.Approved != nil {
+		obj.Approved = EncodeTranslation(t.Approved)
+	}
+
+	return obj
+}
+
+// DecodeTranslation converts the JSON form of a translation back
+func DecodeTranslation(obj *Translation) *i18n.Translation {
+	t := &i18n.Translation{
+		Lang:    language.Make(obj.Lang),
+		Key:     obj.Key,
+		Value:   obj.Value,
+		Plurals: obj.Plurals,
+		ICU:     obj.ICU,
+
+		State:    obj.State,
+		Metadata: decodeMetadata(obj.Metadata),
+	}
+
+	if obj.Approved != nil {
+		t.Approved = DecodeTranslation(obj.Approved)
+	}
+
+	return t
+}
+
+func encodeMetadata(m *i18n.Metadata) *Metadata {
+	if m == nil {
+		return nil
+	}
+
+	return &Metadata{
+		Description: m.Description,
+		Context:     m.Context,
+		MaxLength:   m.MaxLength,
+		Labels:      m.Labels,
+		Created:     encodeTime(m.Created),
+		Updated:     encodeTime(m.Updated),
+		Author:      m.Author,
+		ReviewedBy:  m.ReviewedBy,
+		Reviewed:    encodeTime(m.Reviewed),
+	}
+}
+
+func decodeMetadata(obj *Metadata) *i18n.Metadata {
+	if obj == nil {
+		return nil
+	}
+
+	return &i18n.Metadata{
+		Description: obj.Description,
+		Context:     obj.Context,
+		MaxLength:   obj.MaxLength,
+		Labels:      obj.Labels,
+		Created:     decodeTime(obj.Created),
+		Updated:     decodeTime(obj.Updated),
+		Author:      obj.Author,
+		ReviewedBy:  obj.ReviewedBy,
+		Reviewed:    decodeTime(obj.Reviewed),
+	}
+}
+
+// encodeTime leaves out unset times
+func encodeTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func decodeTime(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+// EncodeChange converts a change to its JSON form
+func EncodeChange(c *i18n.Change) *Change {
+	obj := &Change{
+		Type: c.Type,
+	}
+
+	if c.Translation != nil {
+		obj.Translation = EncodeTranslation(c.Translation)
+	}
+
+	return obj
+}
+
+// DecodeChange converts the JSON form of a change back
+func DecodeChange(obj *Change) *i18n.Change {
+	change := &i18n.Change{
+		Type: obj.Type,
+	}
+
+	if obj.Translation != nil {
+		change.Translation = DecodeTranslation(obj.Translation)
+	}
+
+	return change
+}
@@ -0,0 +1,481 @@
+package i18n
+
+import (
+	"sync"
+
+	"golang.org/x/text/language"
+)
+
+// BaseLayer is the name of the bottom layer of a LayeredStorage
+const BaseLayer = "base"
+
+type layer struct {
+	name    string
+	storage Storage
+}
+
+// LayeredStorage combines storages with an explicit precedence, such as base
+// catalogs with tenant or hotfix overrides on top. Reads are merged top-down,
+// so a translation in a layer overrides the same key and language in every
+// layer beneath it. Writes only go to the writable layer, which is the base
+// layer unless set otherwise.
+type LayeredStorage struct {
+	lock     sync.RWMutex
+	layers   []*layer
+	writable *layer
+}
+
+// NewLayeredStorage creates a layered storage with base as the bottom layer
+func NewLayeredStorage(base Storage) *LayeredStorage {
+	l := &layer{
+		name:    BaseLayer,
+		storage: base,
+	}
+
+	return &LayeredStorage{
+		layers:   []*layer{l},
+		writable: l,
+	}
+}
+
+// AddLayer adds a storage on top of the existing layers, replacing any layer
+// with the same name
+func (storage *LayeredStorage) AddLayer(name string, s Storage) {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
+	for _, l := range storage.layers {
+		if l.name == name {
+			l.storage = s
+			return
+		}
+	}
+
+	storage.layers = append(storage.layers, &layer{
+		name:    name,
+		storage: s,
+	})
+}
+
+// SetWritableLayer sets the layer writes are routed to, ErrNotFound is
+// returned if there is no layer with the name
+func (storage *LayeredStorage) SetWritableLayer(name string) error {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
+	for _, l := range storage.layers {
+		if l.name == name {
+			storage.writable = l
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+// Layers gets the layer names, top layer first
+func (storage *LayeredStorage) Layers() []string {
+	storage.lock.RLock()
+	defer storage.lock.RUnlock()
+
+	names := make([]string, 0, len(storage.layers))
+	for i := len(storage.layers) - 1; i >= 0; i-- {
+		names = append(names, storage.layers[i].name)
+	}
+	return names
+}
+
+// Layer gets the storage of a layer, or nil if there is no layer with the name
+func (storage *LayeredStorage) Layer(name string) Storage {
+	storage.lock.RLock()
+	defer storage.lock.RUnlock()
+
+	for _, l := range storage.layers {
+		if l.name == name {
+			return l.storage
+		}
+	}
+	return nil
+}
+
+// topDown gets the layer storages, top layer first
+func (storage *LayeredStorage) topDown() []Storage {
+	storage.lock.RLock()
+	defer storage.lock.RUnlock()
+
+	storages := make([]Storage, 0, len(storage.layers))
+	for i := len(storage.layers) - 1; i >= 0; i-- {
+		storages = append(storages, storage.layers[i].storage)
+	}
+	return storages
+}
+
+func (storage *LayeredStorage) target() Storage {
+	storage.lock.RLock()
+	defer storage.lock.RUnlock()
+
+	return storage.writable.storage
+}
+
+// Capabilities gets the capabilities of the writable layer
+func (storage *LayeredStorage) Capabilities() Capabilities {
+	return CapabilitiesOf(storage.target())
+}
+
+// GetAll gets the translations of every layer, a translation is only returned
+// from the highest layer that has it
+func (storage *LayeredStorage) GetAll() ([]*Translation, error) {
+	seen := make(map[string]bool)
+	var translations []*Translation
+
+	for _, s := range storage.topDown() {
+		results, err := s.GetAll()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, translation := range results {
+			if k := batchKey(translation); !seen[k] {
+				seen[k] = true
+				translations = append(translations, translation)
+			}
+		}
+	}
+
+	return translations, nil
+}
+
+// GetLanguage gets the translations of a language from every layer, a
+// translation is only returned from the highest layer that has it
+func (storage *LayeredStorage) GetLanguage(tag language.Tag) ([]*Translation, error) {
+	seen := make(map[string]bool)
+	var translations []*Translation
+
+	for _, s := range storage.topDown() {
+		results, err := GetLanguage(s, tag)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, translation := range results {
+			if k := batchKey(translation); !seen[k] {
+				seen[k] = true
+				translations = append(translations, translation)
+			}
+		}
+	}
+
+	return translations, nil
+}
+
+// Store adds a translation to the writable layer, the same translation in a
+// higher layer is still served in its place
+func (storage *LayeredStorage) Store(translation *Translation) error {
+	return storage.target().Store(translation)
+}
+
+// Delete removes a translation from the writable layer, the same translation
+// in another layer is served in its place, if there is one
+func (storage *LayeredStorage) Delete(translation *Translation) error {
+	return storage.target().Delete(translation)
+}
+
+// resolve gets the translation served for the language and key of each
+// translation once it has been stored in or deleted from the writable layer,
+// nil if no layer has one. Only the layers that can shadow the write are read,
+// the layers above the writable layer for a store and every other layer for a
+// delete.
+func (storage *LayeredStorage) resolve(translations []*Translation, deleted bool) ([]*Translation, error) {
+	storage.lock.RLock()
+	layers := make([]*layer, 0, len(storage.layers))
+	for i := len(storage.layers) - 1; i >= 0; i-- {
+		layers = append(layers, storage.layers[i])
+	}
+	writable := storage.writable
+	storage.lock.RUnlock()
+
+	served := make([]*Translation, len(translations))
+
+	byLang := make(map[string][]int)
+	for i, translation := range translations {
+		l := translation.Lang.String()
+		byLang[l] = append(byLang[l], i)
+	}
+
+	for _, indexes := range byLang {
+		tag := translations[indexes[0]].Lang
+
+		remaining := make(map[string][]int, len(indexes))
+		for _, i := range indexes {
+			remaining[translations[i].Key] = append(remaining[translations[i].Key], i)
+		}
+
+		for _, l := range layers {
+			if len(remaining) == 0 {
+				break
+			}
+
+			if l == writable {
+				if deleted {
+					continue
+				}
+
+				for _, pending := range remaining {
+					for _, i := range pending {
+						served[i] = translations[i]
+					}
+				}
+				break
+			}
+
+			results, err := GetLanguage(l.storage, tag)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, translation := range results {
+				for _, i := range remaining[translation.Key] {
+					served[i] = translation
+				}
+				delete(remaining, translation.Key)
+			}
+		}
+	}
+
+	return served, nil
+}
+
+// StoreMany adds translations to the writable layer
+func (storage *LayeredStorage) StoreMany(translations []*Translation) error {
+	return StoreMany(storage.target(), translations)
+}
+
+// DeleteMany removes translations from the writable layer
+func (storage *LayeredStorage) DeleteMany(translations []*Translation) error {
+	return DeleteMany(storage.target(), translations)
+}
+
+// Move stores and deletes translations in the writable layer
+func (storage *LayeredStorage) Move(store []*Translation, remove []*Translation) error {
+	return Move(storage.target(), store, remove)
+}
+
+// ReplaceAll replaces the translations of the writable layer
+func (storage *LayeredStorage) ReplaceAll(translations []*Translation) error {
+	return ReplaceAll(storage.target(), translations)
+}
+
+// DefaultLanguage gets the default language of the highest layer that has one
+func (storage *LayeredStorage) DefaultLanguage() (language.Tag, error) {
+	for _, s := range storage.topDown() {
+		tag, err := s.DefaultLanguage()
+		if err == nil && tag != language.Und {
+			return tag, nil
+		}
+	}
+
+	return language.Und, ErrNotFound
+}
+
+// SupportedLanguages gets the languages supported by any layer
+func (storage *LayeredStorage) SupportedLanguages() ([]language.Tag, error) {
+	seen := make(map[string]bool)
+	var tags []language.Tag
+
+	for _, s := range storage.topDown() {
+		results, err := s.SupportedLanguages()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tag := range results {
+			if !seen[tag.String()] {
+				seen[tag.String()] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	return tags, nil
+}
+
+// SetDefaultLanguage sets the default language of the writable layer
+func (storage *LayeredStorage) SetDefaultLanguage(tag language.Tag) error {
+	return storage.target().SetDefaultLanguage(tag)
+}
+
+// StoreSupportedLanguage adds a supported language to the writable layer
+func (storage *LayeredStorage) StoreSupportedLanguage(tag language.Tag) error {
+	return storage.target().StoreSupportedLanguage(tag)
+}
+
+// DeleteSupportedLanguage removes a supported language from the writable
+// layer
+func (storage *LayeredStorage) DeleteSupportedLanguage(tag language.Tag) error {
+	return storage.target().DeleteSupportedLanguage(tag)
+}
+
+// Fallbacks gets the fallback chains of every layer that stores them, a chain
+// is only returned from the highest layer that has one for the language
+func (storage *LayeredStorage) Fallbacks() ([]*Fallback, error) {
+	seen := make(map[string]bool)
+	var fallbacks []*Fallback
+
+	for _, s := range storage.topDown() {
+		fs, ok := unwrap(s).(FallbackStorage)
+		if !ok {
+			continue
+		}
+
+		results, err := fs.Fallbacks()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, fallback := range results {
+			if !seen[fallback.Lang.String()] {
+				seen[fallback.Lang.String()] = true
+				fallbacks = append(fallbacks, fallback)
+			}
+		}
+	}
+
+	return fallbacks, nil
+}
+
+// StoreFallback sets a fallback chain in the writable layer, ErrUnsupported is
+// returned if the layer does not store fallback chains
+func (storage *LayeredStorage) StoreFallback(fallback *Fallback) error {
+	fs, ok := unwrap(storage.target()).(FallbackStorage)
+	if !ok {
+		return ErrUnsupported
+	}
+	return fs.StoreFallback(fallback)
+}
+
+// DeleteFallback removes a fallback chain from the writable layer,
+// ErrUnsupported is returned if the layer does not store fallback chains
+func (storage *LayeredStorage) DeleteFallback(tag language.Tag) error {
+	fs, ok := unwrap(storage.target()).(FallbackStorage)
+	if !ok {
+		return ErrUnsupported
+	}
+	return fs.DeleteFallback(tag)
+}
+
+// FallbackToDefault gets the setting from the highest layer that stores
+// fallback chains
+func (storage *LayeredStorage) FallbackToDefault() (bool, error) {
+	for _, s := range storage.topDown() {
+		if fs, ok := unwrap(s).(FallbackStorage); ok {
+			return fs.FallbackToDefault()
+		}
+	}
+	return false, nil
+}
+
+// SetFallbackToDefault sets the setting in the writable layer, ErrUnsupported
+// is returned if the layer does not store fallback chains
+func (storage *LayeredStorage) SetFallbackToDefault(enabled bool) error {
+	fs, ok := unwrap(storage.target()).(FallbackStorage)
+	if !ok {
+		return ErrUnsupported
+	}
+	return fs.SetFallbackToDefault(enabled)
+}
+
+// Watch sends changes from every watchable layer as the change to the
+// translation served. A change shadowed by a higher layer is not sent, and a
+// delete revealing a lower layer is sent as an upsert of the translation it
+// reveals. ChangeReload is sent if the other layers can not be read.
+func (storage *LayeredStorage) Watch(stop <-chan struct{}) (<-chan *Change, error) {
+	layers := storage.topDown()
+	merged := make(chan *Change)
+
+	var wait sync.WaitGroup
+
+	for i, s := range layers {
+		watchable, ok := unwrap(s).(WatchableStorage)
+		if !ok {
+			continue
+		}
+
+		changes, err := watchable.Watch(stop)
+		if err != nil {
+			return nil, err
+		}
+
+		wait.Add(1)
+		go func(i int, changes <-chan *Change) {
+			defer wait.Done()
+
+			for change := range changes {
+				if change.Type == ChangeUpsert || change.Type == ChangeDelete {
+					if change = effective(layers, i, change); change == nil {
+						continue
+					}
+				}
+
+				select {
+				case merged <- change:
+				case <-stop:
+					return
+				}
+			}
+		}(i, changes)
+	}
+
+	go func() {
+		wait.Wait()
+		close(merged)
+	}()
+
+	return merged, nil
+}
+
+// effective gets the change to the translation served for an upsert or delete
+// in the layer at index i of layers, top layer first, or nil if a higher layer
+// shadows it
+func effective(layers []Storage, i int, change *Change) *Change {
+	translation := change.Translation
+
+	above, err := findKey(layers[:i], translation.Lang, translation.Key)
+	if err != nil {
+		return &Change{Type: ChangeReload}
+	}
+	if above != nil {
+		return nil
+	}
+
+	if change.Type == ChangeUpsert {
+		return change
+	}
+
+	below, err := findKey(layers[i+1:], translation.Lang, translation.Key)
+	if err != nil {
+		return &Change{Type: ChangeReload}
+	}
+	if below != nil {
+		return &Change{Type: ChangeUpsert, Translation: below}
+	}
+
+	return change
+}
+
+// findKey gets a translation from the first storage that has it, or nil if
+// none do
+func findKey(storages []Storage, lang language.Tag, key string) (*Translation, error) {
+	for _, s := range storages {
+		translations, err := GetLanguage(s, lang)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, translation := range translations {
+			if translation.Key == key {
+				return translation, nil
+			}
+		}
+	}
+	return nil, nil
+}
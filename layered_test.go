@@ -0,0 +1,174 @@
+package i18n
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLayeredStorage(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a base storage with a hotfix layer", t, func() {
+		base := NewInMemoryStorage().(FallbackStorage)
+		hotfix := NewInMemoryStorage().(FallbackStorage)
+
+		base.SetDefaultLanguage(language.English)
+		base.StoreFallback(&Fallback{Lang: language.CanadianFrench, Chain: []language.Tag{language.English}})
+		base.Store(&Translation{Lang: language.English, Key: "SomeKey", Value: "SomeValue"})
+		base.Store(&Translation{Lang: language.English, Key: "OtherKey", Value: "OtherValue"})
+
+		hotfix.StoreSupportedLanguage(language.Spanish)
+		hotfix.StoreFallback(&Fallback{Lang: language.CanadianFrench, Chain: []language.Tag{language.French}})
+		hotfix.Store(&Translation{Lang: language.English, Key: "SomeKey", Value: "FixedValue"})
+
+		storage := NewLayeredStorage(base)
+		storage.AddLayer("hotfix", hotfix)
+
+		i18n := New(storage)
+		err := i18n.Sync()
+		So(err, ShouldBeNil)
+
+		Convey("Then the layers should be listed top layer first", func() {
+			So(storage.Layers(), ShouldResemble, []string{"hotfix", BaseLayer})
+		})
+
+		Convey("Then the top layer should take precedence", func() {
+			So(i18n.T(language.English, "SomeKey"), ShouldEqual, "FixedValue")
+			So(i18n.T(language.English, "OtherKey"), ShouldEqual, "OtherValue")
+			So(i18n.GetFallback(language.CanadianFrench), ShouldResemble, []language.Tag{language.French})
+		})
+
+		Convey("Then languages should be merged from every layer", func() {
+			So(i18n.GetDefaultLanguage(), ShouldResemble, language.English)
+			So(i18n.GetSupportedLanguages(), ShouldHaveLength, 2)
+		})
+
+		Convey("When a translation is added", func() {
+			err := i18n.Add(&Translation{Lang: language.Spanish, Key: "SomeKey", Value: "SomeSpanishValue"})
+			So(err, ShouldBeNil)
+
+			Convey("Then it should only be written to the base layer", func() {
+				results, err := base.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 3)
+
+				results, err = hotfix.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+			})
+		})
+
+		Convey("When a translation shadowed by the hotfix layer is written", func() {
+			err := i18n.Add(&Translation{Lang: language.English, Key: "SomeKey", Value: "OtherValue"})
+			So(err, ShouldBeNil)
+
+			Convey("Then the hotfix layer should still be served", func() {
+				So(i18n.T(language.English, "SomeKey"), ShouldEqual, "FixedValue")
+			})
+
+			Convey("Then deleting it should still serve the hotfix layer", func() {
+				err := i18n.Delete(&Translation{Lang: language.English, Key: "SomeKey"})
+				So(err, ShouldBeNil)
+				So(i18n.T(language.English, "SomeKey"), ShouldEqual, "FixedValue")
+			})
+		})
+
+		Convey("When the hotfix layer is writable", func() {
+			err := storage.SetWritableLayer("hotfix")
+			So(err, ShouldBeNil)
+
+			Convey("Then deleting a translation should reveal the base layer", func() {
+				err := i18n.Delete(&Translation{Lang: language.English, Key: "SomeKey"})
+				So(err, ShouldBeNil)
+				So(i18n.T(language.English, "SomeKey"), ShouldEqual, "SomeValue")
+
+				err = i18n.Sync()
+				So(err, ShouldBeNil)
+				So(i18n.T(language.English, "SomeKey"), ShouldEqual, "SomeValue")
+			})
+		})
+
+		Convey("When an unknown layer is made writable", func() {
+			err := storage.SetWritableLayer("tenant")
+
+			Convey("Then a not found error should be returned", func() {
+				So(err, ShouldEqual, ErrNotFound)
+			})
+		})
+
+		Reset(func() {
+			i18n.Close()
+		})
+	})
+}
+
+func TestLayeredStorageWatch(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a watched base storage with a hotfix layer", t, func() {
+		base := NewInMemoryStorage()
+		hotfix := NewInMemoryStorage()
+
+		base.Store(&Translation{Lang: language.English, Key: "SomeKey", Value: "SomeValue"})
+		base.Store(&Translation{Lang: language.English, Key: "OtherKey", Value: "OtherValue"})
+		hotfix.Store(&Translation{Lang: language.English, Key: "SomeKey", Value: "FixedValue"})
+
+		storage := NewLayeredStorage(base)
+		storage.AddLayer("hotfix", hotfix)
+
+		stop := make(chan struct{})
+		changes, err := storage.Watch(stop)
+		So(err, ShouldBeNil)
+
+		next := func() *Change {
+			select {
+			case change := <-changes:
+				return change
+			case <-time.After(time.Second):
+				return nil
+			}
+		}
+
+		Convey("When a key shadowed by the hotfix layer is stored in the base layer", func() {
+			base.Store(&Translation{Lang: language.English, Key: "SomeKey", Value: "NewValue"})
+			base.Store(&Translation{Lang: language.English, Key: "NewKey", Value: "NewValue"})
+
+			Convey("Then it should not be sent", func() {
+				change := next()
+				So(change, ShouldNotBeNil)
+				So(change.Type, ShouldEqual, ChangeUpsert)
+				So(change.Translation.Key, ShouldEqual, "NewKey")
+			})
+		})
+
+		Convey("When a key is deleted from the hotfix layer", func() {
+			hotfix.Delete(&Translation{Lang: language.English, Key: "SomeKey"})
+
+			Convey("Then the base layer translation should be sent", func() {
+				change := next()
+				So(change, ShouldNotBeNil)
+				So(change.Type, ShouldEqual, ChangeUpsert)
+				So(change.Translation.Value, ShouldEqual, "SomeValue")
+			})
+		})
+
+		Convey("When a key only in the base layer is deleted", func() {
+			base.Delete(&Translation{Lang: language.English, Key: "OtherKey"})
+
+			Convey("Then the delete should be sent", func() {
+				change := next()
+				So(change, ShouldNotBeNil)
+				So(change.Type, ShouldEqual, ChangeDelete)
+				So(change.Translation.Key, ShouldEqual, "OtherKey")
+			})
+		})
+
+		Reset(func() {
+			close(stop)
+		})
+	})
+}
@@ -0,0 +1,626 @@
+package i18n
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/text/language"
+)
+
+// LanguageStorage is implemented by storages that can load the translations
+// of a single language without loading the rest
+type LanguageStorage interface {
+	Storage
+
+	GetLanguage(language.Tag) ([]*Translation, error)
+}
+
+// ContextLanguageStorage is a LanguageStorage whose reads accept a context
+type ContextLanguageStorage interface {
+	LanguageStorage
+
+	GetLanguageContext(context.Context, language.Tag) ([]*Translation, error)
+}
+
+// GetLanguage gets the translations of a language from a storage, storages
+// that do not implement LanguageStorage have every translation loaded and
+// filtered
+func GetLanguage(storage Storage, tag language.Tag) ([]*Translation, error) {
+	if s, ok := unwrap(storage).(LanguageStorage); ok {
+		return s.GetLanguage(tag)
+	}
+
+	all, err := storage.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	return inLanguage(all, tag), nil
+}
+
+// GetLanguageContext gets the translations of a language as GetLanguage,
+// returning early once the context is done
+func GetLanguageContext(ctx context.Context, storage Storage, tag language.Tag) ([]*Translation, error) {
+	switch s := unwrap(storage).(type) {
+	case ContextLanguageStorage:
+		return s.GetLanguageContext(ctx, tag)
+	case LanguageStorage:
+		var translations []*Translation
+		err := Await(ctx, func() (err error) {
+			translations, err = s.GetLanguage(tag)
+			return err
+		})
+		return translations, err
+	}
+
+	all, err := WithContext(storage).GetAllContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return inLanguage(all, tag), nil
+}
+
+// inLanguage filters translations to those in a language
+func inLanguage(translations []*Translation, tag language.Tag) []*Translation {
+	lang := tag.String()
+
+	var filtered []*Translation
+	for _, translation := range translations {
+		if translation.Lang.String() == lang {
+			filtered = append(filtered, translation)
+		}
+	}
+
+	return filtered
+}
+
+// KeyStorage is implemented by storages that can get a single translation
+// without loading the rest of its language
+type KeyStorage interface {
+	Storage
+
+	// GetKey gets the translation of a key in a language, ErrNotFound is
+	// returned if there is none
+	GetKey(language.Tag, string) (*Translation, error)
+}
+
+// ContextKeyStorage is a KeyStorage whose reads accept a context
+type ContextKeyStorage interface {
+	KeyStorage
+
+	GetKeyContext(context.Context, language.Tag, string) (*Translation, error)
+}
+
+// GetKeyContext gets the translation of a key in a language from a storage,
+// returning early once the context is done. Storages that do not implement
+// KeyStorage have the language loaded and searched. ErrNotFound is returned
+// if there is no translation.
+func GetKeyContext(ctx context.Context, storage Storage, tag language.Tag, key string) (*Translation, error) {
+	switch s := unwrap(storage).(type) {
+	case ContextKeyStorage:
+		return s.GetKeyContext(ctx, tag, key)
+	case KeyStorage:
+		var translation *Translation
+		err := Await(ctx, func() (err error) {
+			translation, err = s.GetKey(tag, key)
+			return err
+		})
+		return translation, err
+	}
+
+	translations, err := GetLanguageContext(ctx, storage, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, translation := range translations {
+		if translation.Key == key {
+			return translation, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// maxEmptyLanguages limits how many languages without translations are kept
+// loaded in lazy mode, they cost little but would otherwise never be evicted
+const maxEmptyLanguages = 64
+
+// Failing lazy loads are retried after a backoff between these bounds rather
+// than on every lookup
+const (
+	loadMinBackoff = time.Second
+	loadMaxBackoff = time.Minute
+)
+
+// lazyConfig holds the settings of lazy mode, a limit or budget of 0 means
+// no limit
+type lazyConfig struct {
+	enabled bool
+	limit   int
+	budget  int
+}
+
+// readyCache records the languages of the fallback chain of requested
+// languages once they have all been loaded
+type readyCache struct {
+	lock  sync.Mutex
+	ready atomic.Value
+}
+
+func newReadyCache() *readyCache {
+	cache := new(readyCache)
+	cache.ready.Store(make(map[language.Tag][]string))
+	return cache
+}
+
+func (cache *readyCache) get(tag language.Tag) ([]string, bool) {
+	langs, ok := cache.ready.Load().(map[language.Tag][]string)[tag]
+	return langs, ok
+}
+
+func (cache *readyCache) put(tag language.Tag, langs []string) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	current := cache.ready.Load().(map[language.Tag][]string)
+	if len(current) >= maxChains {
+		return
+	}
+
+	next := make(map[language.Tag][]string, len(current)+1)
+	for t, l := range current {
+		next[t] = l
+	}
+	next[tag] = langs
+
+	cache.ready.Store(next)
+}
+
+// languageUsage records when languages were last used so the least recently
+// used can be evicted, it is shared by every catalog of an I18n
+type languageUsage struct {
+	clock int64
+
+	lock sync.Mutex
+	used atomic.Value
+}
+
+func newLanguageUsage() *languageUsage {
+	usage := new(languageUsage)
+	usage.used.Store(make(map[string]*int64))
+	return usage
+}
+
+func (usage *languageUsage) touch(lang string) {
+	if last, ok := usage.used.Load().(map[string]*int64)[lang]; ok {
+		atomic.StoreInt64(last, atomic.AddInt64(&usage.clock, 1))
+	}
+}
+
+func (usage *languageUsage) last(lang string) int64 {
+	if last, ok := usage.used.Load().(map[string]*int64)[lang]; ok {
+		return atomic.LoadInt64(last)
+	}
+	return 0
+}
+
+func (usage *languageUsage) add(lang string) {
+	usage.lock.Lock()
+	defer usage.lock.Unlock()
+
+	current := usage.used.Load().(map[string]*int64)
+	if _, ok := current[lang]; ok {
+		return
+	}
+
+	next := make(map[string]*int64, len(current)+1)
+	for l, last := range current {
+		next[l] = last
+	}
+	next[lang] = new(int64)
+
+	usage.used.Store(next)
+}
+
+// size estimates the memory used by a translation
+func size(translation *Translation) int {
+	n := len(translation.Key) + len(translation.Value)
+	for _, value := range translation.Plurals {
+		n += len(value)
+	}
+	return n
+}
+
+// withLanguages adds the translations of languages loaded in lazy mode, the
+// languages are marked as loaded even if they have no translations
+func (c *catalog) withLanguages(tags []language.Tag, translations []*Translation, messages []*Message) *catalog {
+	next := c.withTranslations(translations, messages)
+
+	next.loaded = make(map[string]int, len(c.loaded)+len(tags))
+	for lang, n := range c.loaded {
+		next.loaded[lang] = n
+	}
+
+	for _, tag := range tags {
+		next.loaded[tag.String()] = 0
+	}
+
+	for _, translation := range translations {
+		next.loaded[translation.Lang.String()] += size(translation)
+	}
+
+	return next
+}
+
+// withoutLanguage drops the translations of a language loaded in lazy mode,
+// the language keeps its id so it can be loaded again
+func (c *catalog) withoutLanguage(lang string) *catalog {
+	next := c.clone()
+
+	if id, ok := c.langs[lang]; ok {
+		next.entries = make([]map[string]*cacheEntry, len(c.entries))
+		copy(next.entries, c.entries)
+		next.entries[id] = make(map[string]*cacheEntry)
+	}
+
+	next.loaded = make(map[string]int, len(c.loaded))
+	for l, n := range c.loaded {
+		if l != lang {
+			next.loaded[l] = n
+		}
+	}
+
+	return next
+}
+
+// evict drops the least recently used languages until the catalog is within
+// the language limit and memory budget, languages in keep are never dropped.
+// Languages without translations are not counted against the limit or budget
+// but only maxEmptyLanguages of them are kept.
+func (c *catalog) evict(keep map[string]bool) *catalog {
+	for {
+		total, languages, empty := 0, 0, 0
+		for _, n := range c.loaded {
+			if n > 0 {
+				total += n
+				languages++
+			} else {
+				empty++
+			}
+		}
+
+		over := (c.lazy.limit > 0 && languages > c.lazy.limit) ||
+			(c.lazy.budget > 0 && total > c.lazy.budget)
+		if !over && empty <= maxEmptyLanguages {
+			return c
+		}
+
+		coldest := ""
+		for lang, n := range c.loaded {
+			if (n == 0) == over || keep[lang] {
+				continue
+			}
+			if coldest == "" || c.usage.last(lang) < c.usage.last(coldest) {
+				coldest = lang
+			}
+		}
+
+		if coldest == "" {
+			return c
+		}
+
+		c = c.withoutLanguage(coldest)
+	}
+}
+
+// SetLazy sets whether languages are loaded on first use rather than by Sync,
+// Sync then only reloads the languages already loaded. Storages implementing
+// LanguageStorage load a single language at a time. When supported languages
+// are set only those, the default language and languages named by fallback
+// chains are loaded, other requested languages fall back without a load.
+// Keys, Languages and Search only see the languages that have been loaded.
+func (i18n *I18n) SetLazy(enabled bool) {
+	i18n.lock.Lock()
+	defer i18n.lock.Unlock()
+
+	next := i18n.load().clone()
+	next.lazy.enabled = enabled
+	next.loaded = make(map[string]int)
+	i18n.catalog.Store(next)
+}
+
+// SetLanguageLimit sets how many languages are kept loaded in lazy mode, the
+// least recently used languages are evicted first. 0 means no limit.
+func (i18n *I18n) SetLanguageLimit(n int) {
+	i18n.lock.Lock()
+	defer i18n.lock.Unlock()
+
+	next := i18n.load().clone()
+	next.lazy.limit = n
+	i18n.catalog.Store(next.evict(nil))
+}
+
+// SetMemoryBudget sets roughly how many bytes of keys and values are kept
+// loaded in lazy mode, the least recently used languages are evicted first.
+// 0 means no budget.
+func (i18n *I18n) SetMemoryBudget(bytes int) {
+	i18n.lock.Lock()
+	defer i18n.lock.Unlock()
+
+	next := i18n.load().clone()
+	next.lazy.budget = bytes
+	i18n.catalog.Store(next.evict(nil))
+}
+
+// loaded gets the catalog with every language in the fallback chain of the
+// language loaded, in lazy mode missing languages are loaded first
+func (i18n *I18n) loaded(lang language.Tag) *catalog {
+	c := i18n.load()
+	if !c.lazy.enabled {
+		return c
+	}
+
+	if langs, ok := c.ready.get(lang); ok {
+		for _, l := range langs {
+			c.usage.touch(l)
+		}
+		return c
+	}
+
+	return i18n.loadChain(lang)
+}
+
+// loadAttempts bounds how many times a lazy load is read again because a
+// write was published while it was reading
+const loadAttempts = 3
+
+// loadChain loads the languages in the fallback chain of a language that are
+// not loaded yet, evicting cold languages if needed. Loads are serialized by
+// loadLock and read the storages without holding the lock used by writes. A
+// load that a write was published during may be older than the write, so it
+// is discarded and read again, and given up on for this lookup after
+// loadAttempts. If loading fails the current catalog is used and loading is
+// tried again after a backoff.
+func (i18n *I18n) loadChain(lang language.Tag) *catalog {
+	i18n.loadLock.Lock()
+	defer i18n.loadLock.Unlock()
+
+	c := i18n.load()
+	if langs, ok := c.ready.get(lang); ok {
+		for _, l := range langs {
+			c.usage.touch(l)
+		}
+		return c
+	}
+
+	if time.Now().Before(i18n.loadRetry) {
+		return c
+	}
+
+	missing, keep, langs := c.missingLanguages(lang)
+
+	for attempt := 1; len(missing) > 0; attempt++ {
+		translations, messages, err := i18n.getLanguages(context.Background(), missing)
+		if err != nil {
+			i18n.loadFailures++
+			backoff := Backoff(i18n.loadFailures, loadMinBackoff, loadMaxBackoff)
+			i18n.loadRetry = time.Now().Add(backoff)
+
+			i18n.logger().Error("loading languages failed", "lang", lang.String(), "retry", backoff, "error", err)
+			return c
+		}
+
+		i18n.lock.Lock()
+
+		if current := i18n.load(); current != c {
+			i18n.lock.Unlock()
+
+			c = current
+			if attempt == loadAttempts {
+				i18n.logger().Debug("loading languages raced with writes", "lang", lang.String(), "attempts", attempt)
+				return c
+			}
+
+			missing, keep, langs = c.missingLanguages(lang)
+			continue
+		}
+
+		for _, tag := range missing {
+			c.usage.add(tag.String())
+		}
+
+		c = c.withLanguages(missing, translations, messages).evict(keep)
+		i18n.catalog.Store(c)
+		i18n.lock.Unlock()
+
+		i18n.logger().Debug("loaded languages", "lang", lang.String(), "languages", len(missing), "translations", len(translations))
+		break
+	}
+
+	i18n.loadFailures = 0
+
+	for _, l := range langs {
+		c.usage.touch(l)
+	}
+	c.ready.put(lang, langs)
+
+	return c
+}
+
+// missingLanguages gets the languages in the fallback chain of a language
+// that can be loaded but are not yet, along with every language in the chain
+func (c *catalog) missingLanguages(lang language.Tag) ([]language.Tag, map[string]bool, []string) {
+	var missing []language.Tag
+	keep := make(map[string]bool)
+	var langs []string
+
+	for _, tag := range c.chain(lang) {
+		l := tag.String()
+		keep[l] = true
+		langs = append(langs, l)
+
+		if _, ok := c.loaded[l]; !ok && c.loadable(tag) {
+			missing = append(missing, tag)
+		}
+	}
+
+	return missing, keep, langs
+}
+
+// loadable checks if a language is known to the catalog, without supported
+// languages every language is
+func (c *catalog) loadable(tag language.Tag) bool {
+	lang := tag.String()
+	if len(c.supportedLanguages) == 0 || tag == language.Und || lang == c.defaultLanguage.String() {
+		return true
+	}
+
+	for _, supported := range c.supportedLanguages {
+		if supported.String() == lang {
+			return true
+		}
+	}
+
+	for from, chain := range c.fallbacks {
+		if from == lang {
+			return true
+		}
+		for _, to := range chain {
+			if to.String() == lang {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// getLanguages gets the translations of languages from every storage, ICU
+// translations that can not be parsed are skipped. Storages that do not
+// implement LanguageStorage are read once for all the languages.
+func (i18n *I18n) getLanguages(ctx context.Context, tags []language.Tag) ([]*Translation, []*Message, error) {
+	var translations []*Translation
+	var messages []*Message
+
+	if len(tags) == 0 {
+		return nil, nil, nil
+	}
+
+	wanted := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		wanted[tag.String()] = true
+	}
+
+	for _, storage := range i18n.storage {
+		var results []*Translation
+
+		if _, ok := unwrap(storage).(LanguageStorage); ok {
+			for _, tag := range tags {
+				found, err := GetLanguageContext(ctx, storage, tag)
+				if err != nil {
+					return nil, nil, err
+				}
+				results = append(results, found...)
+			}
+		} else {
+			all, err := WithContext(storage).GetAllContext(ctx)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, translation := range all {
+				if wanted[translation.Lang.String()] {
+					results = append(results, translation)
+				}
+			}
+		}
+
+		for _, translation := range results {
+			message, err := translation.message()
+			if err != nil {
+				i18n.logger().Warn("skipping translation that can not be parsed", "lang", translation.Lang.String(), "key", translation.Key, "error", err)
+				continue
+			}
+
+			translations = append(translations, translation)
+			messages = append(messages, message)
+		}
+	}
+
+	return translations, messages, nil
+}
+
+// prefixed reads the translations with keys starting with the prefix from
+// every storage, a translation in a later storage replaces the same one in an
+// earlier storage as in a sync. ICU translations that can not be parsed are
+// skipped. Writable reads only the writable layer of a LayeredStorage.
+func (i18n *I18n) prefixed(ctx context.Context, prefix string, writable bool) ([]*Translation, error) {
+	var translations []*Translation
+	seen := make(map[string]int)
+
+	for _, storage := range i18n.storage {
+		if layered, ok := unwrap(storage).(*LayeredStorage); ok && writable {
+			storage = layered.target()
+		}
+
+		all, err := WithContext(storage).GetAllContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, translation := range all {
+			if !strings.HasPrefix(translation.Key, prefix) {
+				continue
+			}
+			if _, err := translation.message(); err != nil {
+				continue
+			}
+
+			if i, ok := seen[batchKey(translation)]; ok {
+				translations[i] = translation
+				continue
+			}
+
+			seen[batchKey(translation)] = len(translations)
+			translations = append(translations, translation)
+		}
+	}
+
+	return translations, nil
+}
+
+// syncLazy reloads the languages already loaded in lazy mode into an
+// unpublished catalog
+func (i18n *I18n) syncLazy(ctx context.Context, current *catalog, next *catalog) (int, error) {
+	var tags []language.Tag
+	for lang := range current.loaded {
+		tags = append(tags, language.Make(lang))
+	}
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	translations, messages, err := i18n.getLanguages(ctx, tags)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, translation := range translations {
+		next.put(translation, messages[i])
+	}
+
+	next.loaded = make(map[string]int, len(tags))
+	for _, tag := range tags {
+		next.loaded[tag.String()] = 0
+	}
+	for _, translation := range translations {
+		next.loaded[translation.Lang.String()] += size(translation)
+	}
+
+	return len(translations), nil
+}
@@ -0,0 +1,328 @@
+package i18n
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/text/language"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// languageCountingStorage records the languages loaded through GetLanguage
+type languageCountingStorage struct {
+	Storage
+
+	lock      sync.Mutex
+	languages []string
+	all       int
+}
+
+func (storage *languageCountingStorage) GetAll() ([]*Translation, error) {
+	storage.lock.Lock()
+	storage.all++
+	storage.lock.Unlock()
+
+	return storage.Storage.GetAll()
+}
+
+func (storage *languageCountingStorage) GetLanguage(tag language.Tag) ([]*Translation, error) {
+	storage.lock.Lock()
+	storage.languages = append(storage.languages, tag.String())
+	storage.lock.Unlock()
+
+	return GetLanguage(storage.Storage, tag)
+}
+
+func (storage *languageCountingStorage) loads(lang string) int {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
+	n := 0
+	for _, l := range storage.languages {
+		if l == lang {
+			n++
+		}
+	}
+	return n
+}
+
+// hookedStorage runs a hook the first time a language is loaded, and blocks
+// later loads until released once release is set
+type hookedStorage struct {
+	Storage
+
+	once    sync.Once
+	hook    func()
+	release chan struct{}
+}
+
+func (storage *hookedStorage) GetLanguage(tag language.Tag) ([]*Translation, error) {
+	first := false
+	storage.once.Do(func() {
+		first = true
+	})
+
+	if first {
+		storage.hook()
+	} else if storage.release != nil {
+		<-storage.release
+	}
+
+	return GetLanguage(storage.Storage, tag)
+}
+
+func TestLazy(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a lazy i18n instance", t, func() {
+		storage := &languageCountingStorage{Storage: NewInMemoryStorage()}
+		StoreMany(storage.Storage, []*Translation{
+			{Lang: language.English, Key: "hello", Value: "Hello"},
+			{Lang: language.French, Key: "hello", Value: "Bonjour"},
+			{Lang: language.German, Key: "hello", Value: "Hallo"},
+			{Lang: language.German, Key: "bye", Value: "Tschüss"},
+		})
+
+		i18n := New(storage)
+		i18n.SetLazy(true)
+		So(i18n.Sync(), ShouldBeNil)
+
+		Convey("Then nothing should be loaded by sync", func() {
+			So(storage.all, ShouldEqual, 0)
+			So(i18n.Languages(), ShouldBeEmpty)
+		})
+
+		Convey("When a translation is requested", func() {
+			So(i18n.T(language.French, "hello"), ShouldEqual, "Bonjour")
+
+			Convey("Then only its language should be loaded", func() {
+				So(storage.loads("fr"), ShouldEqual, 1)
+				So(storage.loads("de"), ShouldEqual, 0)
+				So(i18n.Languages(), ShouldResemble, []language.Tag{language.French})
+			})
+
+			Convey("Then it should not be loaded again", func() {
+				So(i18n.T(language.French, "hello"), ShouldEqual, "Bonjour")
+				So(storage.loads("fr"), ShouldEqual, 1)
+			})
+
+			Convey("Then sync should only reload the loaded languages", func() {
+				storage.Store(&Translation{Lang: language.French, Key: "hello", Value: "Salut"})
+
+				So(i18n.Sync(), ShouldBeNil)
+				So(storage.all, ShouldEqual, 0)
+				So(storage.loads("fr"), ShouldEqual, 2)
+				So(i18n.Languages(), ShouldResemble, []language.Tag{language.French})
+				So(i18n.T(language.French, "hello"), ShouldEqual, "Salut")
+			})
+		})
+
+		Convey("When a translation is requested in a child language", func() {
+			So(i18n.T(language.BritishEnglish, "hello"), ShouldEqual, "Hello")
+
+			Convey("Then the fallback chain should be loaded", func() {
+				So(storage.loads("en-GB"), ShouldEqual, 1)
+				So(storage.loads("en"), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the language limit is reached", func() {
+			i18n.SetLanguageLimit(1)
+
+			So(i18n.T(language.French, "hello"), ShouldEqual, "Bonjour")
+			So(i18n.T(language.German, "hello"), ShouldEqual, "Hallo")
+
+			Convey("Then the least recently used language should be evicted", func() {
+				So(i18n.Languages(), ShouldResemble, []language.Tag{language.German})
+			})
+
+			Convey("Then an evicted language should be loaded again on use", func() {
+				So(i18n.T(language.French, "hello"), ShouldEqual, "Bonjour")
+				So(storage.loads("fr"), ShouldEqual, 2)
+				So(i18n.Languages(), ShouldResemble, []language.Tag{language.French})
+			})
+		})
+
+		Convey("When the memory budget is reached", func() {
+			i18n.SetMemoryBudget(len("hello") + len("Bonjour") + len("hello") + len("Hello"))
+
+			So(i18n.T(language.French, "hello"), ShouldEqual, "Bonjour")
+			So(i18n.T(language.English, "hello"), ShouldEqual, "Hello")
+			So(i18n.Languages(), ShouldResemble, []language.Tag{language.English, language.French})
+
+			So(i18n.T(language.German, "bye"), ShouldEqual, "Tschüss")
+
+			Convey("Then languages should be evicted until it fits", func() {
+				So(i18n.Languages(), ShouldResemble, []language.Tag{language.German})
+			})
+		})
+	})
+
+	Convey("Given a lazy i18n instance with supported languages", t, func() {
+		storage := &languageCountingStorage{Storage: NewInMemoryStorage()}
+		StoreMany(storage.Storage, []*Translation{
+			{Lang: language.English, Key: "hello", Value: "Hello"},
+			{Lang: language.German, Key: "hello", Value: "Hallo"},
+		})
+
+		i18n := New(storage)
+		i18n.SetLazy(true)
+		So(i18n.SetDefaultLanguage(language.English), ShouldBeNil)
+		So(i18n.SetFallbackToDefault(true), ShouldBeNil)
+
+		Convey("When an unsupported language is requested", func() {
+			So(i18n.T(language.German, "hello"), ShouldEqual, "Hello")
+
+			Convey("Then it should not be loaded", func() {
+				So(storage.loads("de"), ShouldEqual, 0)
+				So(storage.loads("en"), ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given a lazy i18n instance asked for many unknown languages", t, func() {
+		i18n := New()
+		i18n.SetLazy(true)
+
+		for n := 0; n < 2*maxEmptyLanguages; n++ {
+			i18n.T(language.Make(fmt.Sprintf("und-x-lang%d", n)), "hello")
+		}
+
+		Convey("Then only a bounded number should stay loaded", func() {
+			So(len(i18n.load().loaded), ShouldBeLessThanOrEqualTo, maxEmptyLanguages)
+		})
+	})
+
+	Convey("Given a lazy i18n instance whose storage fails", t, func() {
+		storage := &flakyStorage{Storage: NewInMemoryStorage(), failures: 1}
+		storage.Store(&Translation{Lang: language.French, Key: "hello", Value: "Bonjour"})
+
+		i18n := New(storage)
+		i18n.SetLazy(true)
+
+		So(i18n.T(language.French, "hello"), ShouldBeEmpty)
+
+		Convey("When the language is requested again", func() {
+			So(i18n.T(language.French, "hello"), ShouldBeEmpty)
+
+			Convey("Then loading should wait for the backoff", func() {
+				So(storage.calls, ShouldHaveLength, 1)
+			})
+		})
+
+		Convey("When the backoff has passed", func() {
+			i18n.loadRetry = time.Time{}
+
+			Convey("Then the language should be loaded", func() {
+				So(i18n.T(language.French, "hello"), ShouldEqual, "Bonjour")
+				So(storage.calls, ShouldHaveLength, 2)
+			})
+		})
+	})
+
+	Convey("Given a lazy i18n instance with a group in several languages", t, func() {
+		storage := NewInMemoryStorage()
+		StoreMany(storage, []*Translation{
+			{Lang: language.English, Key: "greeting.hello", Value: "Hello"},
+			{Lang: language.French, Key: "greeting.hello", Value: "Bonjour"},
+			{Lang: language.German, Key: "greeting.hello", Value: "Hallo"},
+		})
+
+		i18n := New(storage)
+		i18n.SetLazy(true)
+		So(i18n.T(language.French, "greeting.hello"), ShouldEqual, "Bonjour")
+
+		Convey("When the group is moved", func() {
+			So(i18n.Group("greeting").MoveTo("welcome"), ShouldBeNil)
+
+			Convey("Then languages that were not loaded should be moved too", func() {
+				results, err := storage.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 3)
+				for _, result := range results {
+					So(result.Key, ShouldEqual, "welcome.hello")
+				}
+
+				So(i18n.T(language.German, "welcome.hello"), ShouldEqual, "Hallo")
+				So(i18n.T(language.French, "greeting.hello"), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When the group is deleted", func() {
+			So(i18n.Group("greeting").DeleteAll(), ShouldBeNil)
+
+			Convey("Then languages that were not loaded should be deleted too", func() {
+				results, err := storage.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given a storage that can not load a single language", t, func() {
+		storage := &flakyStorage{Storage: NewInMemoryStorage()}
+		StoreMany(storage, []*Translation{
+			{Lang: language.English, Key: "hello", Value: "Hello"},
+			{Lang: language.French, Key: "hello", Value: "Bonjour"},
+		})
+
+		Convey("When a language is loaded", func() {
+			translations, err := GetLanguage(storage, language.French)
+
+			Convey("Then only its translations should be returned", func() {
+				So(err, ShouldBeNil)
+				So(translations, ShouldHaveLength, 1)
+				So(translations[0].Value, ShouldEqual, "Bonjour")
+			})
+		})
+	})
+
+	Convey("Given a lazy i18n instance written to while loading", t, func() {
+		storage := &hookedStorage{Storage: NewInMemoryStorage()}
+		storage.Store(&Translation{Lang: language.French, Key: "hello", Value: "Bonjour"})
+
+		i18n := New(storage)
+		i18n.SetLazy(true)
+
+		var err error
+		storage.hook = func() {
+			err = i18n.Add(&Translation{Lang: language.French, Key: "hello", Value: "Salut"})
+		}
+
+		Convey("When the language is loaded", func() {
+			value := i18n.T(language.French, "hello")
+
+			Convey("Then the load should be read again rather than lose the write", func() {
+				So(err, ShouldBeNil)
+				So(value, ShouldEqual, "Salut")
+			})
+		})
+	})
+
+	Convey("Given a lazy i18n instance whose storage hangs", t, func() {
+		storage := &hookedStorage{Storage: NewInMemoryStorage(), hook: func() {}}
+		storage.Store(&Translation{Lang: language.French, Key: "hello", Value: "Bonjour"})
+
+		i18n := New(storage)
+		i18n.SetLazy(true)
+		So(i18n.T(language.French, "hello"), ShouldEqual, "Bonjour")
+
+		storage.release = make(chan struct{})
+
+		Convey("When it is synced with a deadline", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			err := i18n.SyncContext(ctx)
+			close(storage.release)
+
+			Convey("Then the sync should give up at the deadline", func() {
+				So(err, ShouldEqual, context.DeadlineExceeded)
+			})
+		})
+	})
+}
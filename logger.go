@@ -0,0 +1,38 @@
+package i18n
+
+// Logger receives structured log events, args are alternating keys and values.
+// It is satisfied by *slog.Logger.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Warn(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
+
+// NopLogger discards every event, it is used when no logger is set
+var NopLogger Logger = nopLogger{}
+
+// SetLogger sets the logger for syncs, watched changes and Matchers using this
+// I18n, nil discards events
+func (i18n *I18n) SetLogger(logger Logger) {
+	i18n.lock.Lock()
+	defer i18n.lock.Unlock()
+
+	next := i18n.load().clone()
+	next.logger = logger
+	i18n.catalog.Store(next)
+}
+
+func (i18n *I18n) logger() Logger {
+	if logger := i18n.load().logger; logger != nil {
+		return logger
+	}
+	return NopLogger
+}
@@ -0,0 +1,97 @@
+package i18n
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"golang.org/x/text/language"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// syncBuffer is a buffer that is safe for concurrent writes
+type syncBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
+
+func TestLogger(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given an I18n logging to slog", t, func() {
+		out := &syncBuffer{}
+		logger := slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+		i18n := New()
+		i18n.SetLogger(logger)
+		i18n.SetDefaultLanguage(language.English)
+		i18n.Add(&Translation{
+			Lang:  language.English,
+			Key:   "SomeKey",
+			Value: "SomeValue",
+		})
+
+		Convey("When the I18n is synced", func() {
+			i18n.Sync()
+
+			Convey("Then the start and end of the sync should be logged", func() {
+				So(out.String(), ShouldContainSubstring, `msg="sync started" storages=1`)
+				So(out.String(), ShouldContainSubstring, `msg="sync finished"`)
+				So(out.String(), ShouldContainSubstring, `translations=1`)
+			})
+		})
+
+		Convey("When a sync fails", func() {
+			failing := New(&flakyStorage{Storage: NewInMemoryStorage(), failures: 1})
+			failing.SetLogger(logger)
+			failing.Sync()
+
+			Convey("Then the error should be logged", func() {
+				So(out.String(), ShouldContainSubstring, `level=ERROR msg="sync failed"`)
+			})
+		})
+
+		Convey("When a request is redirected", func() {
+			server := httptest.NewServer(NewMatcher(i18n).Wrapper(nil))
+			defer server.Close()
+
+			http.Get(server.URL + "/page")
+
+			Convey("Then the reason should be logged", func() {
+				So(out.String(), ShouldContainSubstring, `msg="redirecting request" path=/page language=en reason="no language in path"`)
+			})
+		})
+
+		Convey("When a matcher has its own logger", func() {
+			own := &syncBuffer{}
+			matcher := NewMatcher(i18n)
+			matcher.SetLogger(slog.New(slog.NewTextHandler(own, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+			server := httptest.NewServer(matcher.Wrapper(nil))
+			defer server.Close()
+
+			http.Get(server.URL + "/page")
+
+			Convey("Then redirects should be logged to it", func() {
+				So(own.String(), ShouldContainSubstring, `msg="redirecting request"`)
+				So(out.String(), ShouldNotContainSubstring, `msg="redirecting request"`)
+			})
+		})
+	})
+}
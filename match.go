@@ -17,7 +17,8 @@ var (
 
 // Matcher get parses languages from http requests
 type Matcher struct {
-	i18n *I18n
+	i18n   *I18n
+	logger Logger
 }
 
 // NewMatcher creates a new matcher
@@ -27,6 +28,19 @@ func NewMatcher(i18n *I18n) *Matcher {
 	}
 }
 
+// SetLogger sets the logger for redirect decisions, by default the logger of
+// the I18n is used
+func (matcher *Matcher) SetLogger(logger Logger) {
+	matcher.logger = logger
+}
+
+func (matcher *Matcher) log() Logger {
+	if matcher.logger != nil {
+		return matcher.logger
+	}
+	return matcher.i18n.logger()
+}
+
 func (matcher *Matcher) stripPrefix(prefix string, r *http.Request) {
 	if p := strings.TrimPrefix(r.URL.Path, prefix); len(p) < len(r.URL.Path) {
 		r.URL.Path = p
@@ -62,10 +76,13 @@ func GetLanguageFromContext(ctx context.Context) language.Tag {
 }
 
 // Wrapper returns a http.Handler that stores languages in a sharded map for
-// retrieval using GetLanguageFromRequest
+// retrieval using GetLanguageFromRequest, the language is also stored in the
+// request context for use with the Ctx translation methods
 func (matcher *Matcher) Wrapper(handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if tag, redirect := matcher.handle(w, r); !redirect {
+			r = r.WithContext(NewLanguageContext(r.Context(), tag))
+
 			languages.Add(r, tag)
 			defer languages.Delete(r)
 
@@ -152,7 +169,12 @@ func (matcher *Matcher) handle(w http.ResponseWriter, r *http.Request) (language
 		segments[1] = str
 	}
 
-	if !match || !valid || !exact {
+	redirect := !match || !valid || !exact
+	matcher.observe(r, tag, redirect)
+
+	if redirect {
+		matcher.log().Debug("redirecting request", "path", path, "language", tag.String(), "reason", reason(match, valid, exact))
+
 		r.URL.Path = strings.Join(segments, "/")
 		http.Redirect(w, r, r.URL.String(), 302)
 		return tag, true
@@ -161,6 +183,29 @@ func (matcher *Matcher) handle(w http.ResponseWriter, r *http.Request) (language
 	return tag, false
 }
 
+// observe reports a match to the observer of the I18n if one is set
+func (matcher *Matcher) observe(r *http.Request, tag language.Tag, redirect bool) {
+	if observer := matcher.i18n.load().observer; observer != nil {
+		observer.ObserveMatch(r.Context(), &MatchEvent{
+			Tag:      tag,
+			Redirect: redirect,
+		})
+	}
+}
+
+// reason describes why a request is redirected
+func reason(match bool, valid bool, exact bool) string {
+	switch {
+	case !valid:
+		return "no language in path"
+	case !match:
+		return "language not supported"
+	case !exact:
+		return "matched parent language"
+	}
+	return ""
+}
+
 func (matcher *Matcher) tagString(tag language.Tag) string {
 	str := tag.String()
 	if str == "und" {
@@ -53,6 +53,10 @@ func TestMatch(t *testing.T) {
 				So(handler.Tag, ShouldResemble, language.Spanish)
 				So(handler.Request.URL.Path, ShouldEqual, "/other")
 			})
+
+			Convey("Then the lang should be stored in the request context", func() {
+				So(GetLanguageFromContext(handler.Request.Context()), ShouldResemble, language.Spanish)
+			})
 		})
 
 		Convey("When I go to url with valid child lang", func() {
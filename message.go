@@ -0,0 +1,624 @@
+package i18n
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// SyntaxError is returned when an ICU MessageFormat pattern can not be parsed
+type SyntaxError struct {
+	Lang   language.Tag
+	Key    string
+	Offset int
+	Reason string
+}
+
+func (err *SyntaxError) Error() string {
+	if err.Key == "" {
+		return fmt.Sprintf("i18n: syntax error at offset %d: %s", err.Offset, err.Reason)
+	}
+	return fmt.Sprintf("i18n: syntax error in %q (%s) at offset %d: %s", err.Key, err.Lang.String(), err.Offset, err.Reason)
+}
+
+// Message is a parsed ICU MessageFormat pattern
+type Message struct {
+	nodes []messageNode
+}
+
+// ParseMessage parses an ICU MessageFormat pattern
+func ParseMessage(pattern string) (*Message, error) {
+	p := &messageParser{pattern: pattern}
+
+	nodes, err := p.parseMessage(false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{nodes: nodes}, nil
+}
+
+// Format evaluates the message with the arguments, numbers are formatted and
+// plural categories chosen using the rules of lang. Dates and times use fixed
+// layouts that are not localized.
+func (msg *Message) Format(lang language.Tag, args Args) (string, error) {
+	state := &messageState{
+		lang:    lang,
+		printer: message.NewPrinter(lang),
+		args:    args,
+	}
+
+	var buf bytes.Buffer
+	if err := state.format(&buf, msg.nodes); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+type messageNode interface{}
+
+type textNode string
+
+type hashNode struct{}
+
+type argNode struct {
+	name  string
+	typ   string
+	style string
+}
+
+type pluralNode struct {
+	name    string
+	ordinal bool
+	offset  float64
+	exact   map[float64][]messageNode
+	forms   map[PluralForm][]messageNode
+}
+
+type selectNode struct {
+	name  string
+	cases map[string][]messageNode
+}
+
+type messageParser struct {
+	pattern string
+	pos     int
+}
+
+func (p *messageParser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{
+		Offset: p.pos,
+		Reason: fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *messageParser) eof() bool {
+	return p.pos >= len(p.pattern)
+}
+
+func (p *messageParser) skipSpace() {
+	for !p.eof() && strings.IndexByte(" \t\r\n", p.pattern[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *messageParser) token(stop string) string {
+	start := p.pos
+	for !p.eof() && strings.IndexByte(stop, p.pattern[p.pos]) < 0 {
+		p.pos++
+	}
+	return p.pattern[start:p.pos]
+}
+
+func (p *messageParser) expect(c byte) error {
+	p.skipSpace()
+	if p.eof() || p.pattern[p.pos] != c {
+		return p.errorf("expected '%c'", c)
+	}
+	p.pos++
+	return nil
+}
+
+// parseMessage parses text and arguments, a nested message ends at the first
+// unmatched closing brace which is left for the caller
+func (p *messageParser) parseMessage(nested bool, inPlural bool) ([]messageNode, error) {
+	var nodes []messageNode
+	var text bytes.Buffer
+
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, textNode(text.String()))
+			text.Reset()
+		}
+	}
+
+	for !p.eof() {
+		c := p.pattern[p.pos]
+
+		switch {
+		case c == '\'':
+			p.pos++
+			p.parseQuote(&text, inPlural)
+		case c == '{':
+			flush()
+			node, err := p.parseArgument(inPlural)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, node)
+		case c == '}':
+			if !nested {
+				return nil, p.errorf("unexpected '}'")
+			}
+			flush()
+			return nodes, nil
+		case c == '#' && inPlural:
+			flush()
+			nodes = append(nodes, hashNode{})
+			p.pos++
+		default:
+			text.WriteByte(c)
+			p.pos++
+		}
+	}
+
+	if nested {
+		return nil, p.errorf("unterminated message, expected '}'")
+	}
+
+	flush()
+	return nodes, nil
+}
+
+// parseQuote handles an apostrophe, two apostrophes are a literal apostrophe
+// and an apostrophe before a special character quotes text up to the next
+// apostrophe
+func (p *messageParser) parseQuote(text *bytes.Buffer, inPlural bool) {
+	if p.eof() {
+		text.WriteByte('\'')
+		return
+	}
+
+	c := p.pattern[p.pos]
+	if c == '\'' {
+		text.WriteByte('\'')
+		p.pos++
+		return
+	}
+
+	if c != '{' && c != '}' && c != '|' && !(c == '#' && inPlural) {
+		text.WriteByte('\'')
+		return
+	}
+
+	for !p.eof() {
+		c := p.pattern[p.pos]
+		p.pos++
+
+		if c != '\'' {
+			text.WriteByte(c)
+			continue
+		}
+
+		if p.eof() || p.pattern[p.pos] != '\'' {
+			return
+		}
+
+		text.WriteByte('\'')
+		p.pos++
+	}
+}
+
+func (p *messageParser) parseArgument(inPlural bool) (messageNode, error) {
+	p.pos++
+	p.skipSpace()
+
+	name := p.token(" \t\r\n,{}")
+	if name == "" {
+		return nil, p.errorf("expected argument name")
+	}
+
+	p.skipSpace()
+	if p.eof() {
+		return nil, p.errorf("unterminated argument %q", name)
+	}
+
+	if p.pattern[p.pos] == '}' {
+		p.pos++
+		return &argNode{name: name}, nil
+	}
+
+	if err := p.expect(','); err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	typ := p.token(" \t\r\n,{}")
+
+	switch typ {
+	case "plural", "selectordinal":
+		if err := p.expect(','); err != nil {
+			return nil, err
+		}
+		return p.parsePlural(name, typ == "selectordinal")
+	case "select":
+		if err := p.expect(','); err != nil {
+			return nil, err
+		}
+		return p.parseSelect(name, inPlural)
+	case "number", "date", "time", "spellout", "ordinal", "duration":
+		node := &argNode{name: name, typ: typ}
+
+		p.skipSpace()
+		if !p.eof() && p.pattern[p.pos] == ',' {
+			p.pos++
+			node.style = strings.TrimSpace(p.token("{}"))
+		}
+
+		if err := p.expect('}'); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case "":
+		return nil, p.errorf("expected argument type for %q", name)
+	}
+
+	return nil, p.errorf("unknown argument type %q", typ)
+}
+
+func (p *messageParser) parsePlural(name string, ordinal bool) (messageNode, error) {
+	node := &pluralNode{
+		name:    name,
+		ordinal: ordinal,
+		exact:   make(map[float64][]messageNode),
+		forms:   make(map[PluralForm][]messageNode),
+	}
+
+	p.skipSpace()
+	if strings.HasPrefix(p.pattern[p.pos:], "offset:") {
+		p.pos += len("offset:")
+		p.skipSpace()
+
+		offset, err := strconv.ParseFloat(p.token(" \t\r\n{}"), 64)
+		if err != nil {
+			return nil, p.errorf("invalid plural offset")
+		}
+		node.offset = offset
+	}
+
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil, p.errorf("unterminated plural argument %q", name)
+		}
+
+		if p.pattern[p.pos] == '}' {
+			p.pos++
+			break
+		}
+
+		selector := p.token(" \t\r\n{}")
+		if selector == "" {
+			return nil, p.errorf("expected plural selector")
+		}
+
+		if err := p.expect('{'); err != nil {
+			return nil, err
+		}
+
+		nodes, err := p.parseMessage(true, true)
+		if err != nil {
+			return nil, err
+		}
+		p.pos++
+
+		if strings.HasPrefix(selector, "=") {
+			n, err := strconv.ParseFloat(selector[1:], 64)
+			if err != nil {
+				return nil, p.errorf("invalid plural selector %q", selector)
+			}
+			node.exact[n] = nodes
+			continue
+		}
+
+		form := PluralForm(selector)
+		switch form {
+		case PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther:
+			node.forms[form] = nodes
+		default:
+			return nil, p.errorf("invalid plural selector %q", selector)
+		}
+	}
+
+	if _, ok := node.forms[PluralOther]; !ok {
+		return nil, p.errorf("plural argument %q requires an other case", name)
+	}
+
+	return node, nil
+}
+
+func (p *messageParser) parseSelect(name string, inPlural bool) (messageNode, error) {
+	node := &selectNode{
+		name:  name,
+		cases: make(map[string][]messageNode),
+	}
+
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil, p.errorf("unterminated select argument %q", name)
+		}
+
+		if p.pattern[p.pos] == '}' {
+			p.pos++
+			break
+		}
+
+		selector := p.token(" \t\r\n{}")
+		if selector == "" {
+			return nil, p.errorf("expected select selector")
+		}
+
+		if err := p.expect('{'); err != nil {
+			return nil, err
+		}
+
+		nodes, err := p.parseMessage(true, inPlural)
+		if err != nil {
+			return nil, err
+		}
+		p.pos++
+
+		node.cases[selector] = nodes
+	}
+
+	if _, ok := node.cases["other"]; !ok {
+		return nil, p.errorf("select argument %q requires an other case", name)
+	}
+
+	return node, nil
+}
+
+type messageState struct {
+	lang    language.Tag
+	printer *message.Printer
+	args    Args
+	numbers []float64
+}
+
+func (state *messageState) arg(name string) (interface{}, error) {
+	value, ok := state.args[name]
+	if !ok {
+		return nil, fmt.Errorf("i18n: missing argument %q", name)
+	}
+	return value, nil
+}
+
+func (state *messageState) number(name string) (float64, error) {
+	value, err := state.arg(name)
+	if err != nil {
+		return 0, err
+	}
+
+	n, ok := toNumber(value)
+	if !ok {
+		return 0, fmt.Errorf("i18n: argument %q is not a number", name)
+	}
+
+	return n, nil
+}
+
+func (state *messageState) format(buf *bytes.Buffer, nodes []messageNode) error {
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case textNode:
+			buf.WriteString(string(node))
+		case hashNode:
+			if len(state.numbers) == 0 {
+				buf.WriteByte('#')
+				continue
+			}
+			buf.WriteString(state.formatNumber(state.numbers[len(state.numbers)-1], ""))
+		case *argNode:
+			if err := state.formatArg(buf, node); err != nil {
+				return err
+			}
+		case *pluralNode:
+			if err := state.formatPlural(buf, node); err != nil {
+				return err
+			}
+		case *selectNode:
+			value, err := state.arg(node.name)
+			if err != nil {
+				return err
+			}
+
+			nodes, ok := node.cases[fmt.Sprint(value)]
+			if !ok {
+				nodes = node.cases["other"]
+			}
+
+			if err := state.format(buf, nodes); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (state *messageState) formatArg(buf *bytes.Buffer, node *argNode) error {
+	value, err := state.arg(node.name)
+	if err != nil {
+		return err
+	}
+
+	switch node.typ {
+	case "date", "time":
+		t, ok := value.(time.Time)
+		if !ok {
+			return fmt.Errorf("i18n: argument %q is not a time", node.name)
+		}
+		buf.WriteString(t.Format(timeLayout(node.typ, node.style)))
+	case "":
+		if n, ok := toNumber(value); ok && !isString(value) {
+			buf.WriteString(state.formatNumber(n, ""))
+		} else {
+			fmt.Fprint(buf, value)
+		}
+	default:
+		n, err := state.number(node.name)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(state.formatNumber(n, node.style))
+	}
+
+	return nil
+}
+
+func (state *messageState) formatPlural(buf *bytes.Buffer, node *pluralNode) error {
+	n, err := state.number(node.name)
+	if err != nil {
+		return err
+	}
+
+	nodes, ok := node.exact[n]
+	if !ok {
+		rules := plural.Cardinal
+		if node.ordinal {
+			rules = plural.Ordinal
+		}
+
+		form := matchPlural(rules, state.lang, n-node.offset)
+		if nodes, ok = node.forms[form]; !ok {
+			nodes = node.forms[PluralOther]
+		}
+	}
+
+	state.numbers = append(state.numbers, n-node.offset)
+	defer func() {
+		state.numbers = state.numbers[:len(state.numbers)-1]
+	}()
+
+	return state.format(buf, nodes)
+}
+
+func (state *messageState) formatNumber(n float64, style string) string {
+	switch style {
+	case "integer":
+		return state.printer.Sprint(number.Decimal(n, number.MaxFractionDigits(0)))
+	case "percent":
+		return state.printer.Sprint(number.Percent(n))
+	}
+
+	if n == math.Trunc(n) && math.Abs(n) < 1e15 {
+		return state.printer.Sprint(number.Decimal(int64(n)))
+	}
+
+	return state.printer.Sprint(number.Decimal(n))
+}
+
+func matchPlural(rules *plural.Rules, lang language.Tag, n float64) PluralForm {
+	digits := strconv.FormatFloat(math.Abs(n), 'f', -1, 64)
+
+	i, v, f := digits, 0, 0
+	if dot := strings.IndexByte(digits, '.'); dot >= 0 {
+		i = digits[:dot]
+		v = len(digits) - dot - 1
+		f, _ = strconv.Atoi(digits[dot+1:])
+	}
+
+	integer, _ := strconv.Atoi(i)
+	if len(i) > 7 {
+		integer, _ = strconv.Atoi(i[len(i)-7:])
+	}
+
+	return pluralForms[rules.MatchPlural(lang, integer, v, v, f, f)]
+}
+
+func timeLayout(typ string, style string) string {
+	if typ == "time" {
+		switch style {
+		case "short":
+			return "3:04 PM"
+		case "long", "full":
+			return "3:04:05 PM MST"
+		}
+		return "3:04:05 PM"
+	}
+
+	switch style {
+	case "short":
+		return "1/2/06"
+	case "long":
+		return "January 2, 2006"
+	case "full":
+		return "Monday, January 2, 2006"
+	}
+	return "Jan 2, 2006"
+}
+
+func isString(value interface{}) bool {
+	_, ok := value.(string)
+	return ok
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// message parses the value of an ICU translation, other translations have no
+// message
+func (translation *Translation) message() (*Message, error) {
+	if !translation.ICU {
+		return nil, nil
+	}
+
+	msg, err := ParseMessage(translation.Value)
+	if err, ok := err.(*SyntaxError); ok {
+		err.Lang = translation.Lang
+		err.Key = translation.Key
+		return nil, err
+	}
+
+	return msg, err
+}
@@ -0,0 +1,110 @@
+package i18n
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func format(lang language.Tag, pattern string, args Args) string {
+	msg, err := ParseMessage(pattern)
+	So(err, ShouldBeNil)
+
+	result, err := msg.Format(lang, args)
+	So(err, ShouldBeNil)
+
+	return result
+}
+
+func TestMessage(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given ICU MessageFormat patterns", t, func() {
+		Convey("Then simple arguments should be substituted", func() {
+			So(format(language.English, "Hello {name}!", Args{"name": "Bob"}), ShouldEqual, "Hello Bob!")
+		})
+
+		Convey("Then numbers should be formatted for the language", func() {
+			So(format(language.English, "{n}", Args{"n": 1234567}), ShouldEqual, "1,234,567")
+			So(format(language.German, "{n, number}", Args{"n": 1234.5}), ShouldEqual, "1.234,5")
+			So(format(language.English, "{n, number, percent}", Args{"n": 0.25}), ShouldEqual, "25%")
+		})
+
+		Convey("Then plural arguments should use the plural rules of the language", func() {
+			pattern := "{count, plural, =0 {no files} one {# file} other {# files}}"
+			So(format(language.English, pattern, Args{"count": 0}), ShouldEqual, "no files")
+			So(format(language.English, pattern, Args{"count": 1}), ShouldEqual, "1 file")
+			So(format(language.English, pattern, Args{"count": 1000}), ShouldEqual, "1,000 files")
+
+			pattern = "{count, plural, one {# plik} few {# pliki} many {# plików} other {# pliku}}"
+			So(format(language.Polish, pattern, Args{"count": 3}), ShouldEqual, "3 pliki")
+			So(format(language.Polish, pattern, Args{"count": 5}), ShouldEqual, "5 plików")
+			So(format(language.Polish, pattern, Args{"count": 1.5}), ShouldEqual, "1,5 pliku")
+		})
+
+		Convey("Then plural offsets should be applied", func() {
+			pattern := "{n, plural, offset:1 =0 {nobody} =1 {{name}} one {{name} and # other} other {{name} and # others}}"
+			So(format(language.English, pattern, Args{"n": 1, "name": "Bob"}), ShouldEqual, "Bob")
+			So(format(language.English, pattern, Args{"n": 2, "name": "Bob"}), ShouldEqual, "Bob and 1 other")
+			So(format(language.English, pattern, Args{"n": 3, "name": "Bob"}), ShouldEqual, "Bob and 2 others")
+		})
+
+		Convey("Then ordinal arguments should use the ordinal rules of the language", func() {
+			pattern := "{n, selectordinal, one {#st} two {#nd} few {#rd} other {#th}}"
+			So(format(language.English, pattern, Args{"n": 1}), ShouldEqual, "1st")
+			So(format(language.English, pattern, Args{"n": 22}), ShouldEqual, "22nd")
+			So(format(language.English, pattern, Args{"n": 13}), ShouldEqual, "13th")
+		})
+
+		Convey("Then select arguments should choose a case", func() {
+			pattern := "{gender, select, female {She} male {He} other {They}} replied"
+			So(format(language.English, pattern, Args{"gender": "female"}), ShouldEqual, "She replied")
+			So(format(language.English, pattern, Args{"gender": "unknown"}), ShouldEqual, "They replied")
+		})
+
+		Convey("Then nested arguments should be evaluated", func() {
+			pattern := "{gender, select, female {{count, plural, one {She has # file} other {She has # files}}} other {{count, plural, one {They have # file} other {They have # files}}}}"
+			So(format(language.English, pattern, Args{"gender": "female", "count": 2}), ShouldEqual, "She has 2 files")
+			So(format(language.English, pattern, Args{"gender": "x", "count": 1}), ShouldEqual, "They have 1 file")
+		})
+
+		Convey("Then dates should be formatted", func() {
+			date := time.Date(2016, time.March, 4, 0, 0, 0, 0, time.UTC)
+			So(format(language.English, "{d, date, long}", Args{"d": date}), ShouldEqual, "March 4, 2016")
+		})
+
+		Convey("Then apostrophes should quote special characters", func() {
+			So(format(language.English, "It''s '{name}' and it's {name}", Args{"name": "Bob"}), ShouldEqual, "It's {name} and it's Bob")
+		})
+
+		Convey("Then a missing argument should return an error", func() {
+			msg, err := ParseMessage("Hello {name}")
+			So(err, ShouldBeNil)
+
+			_, err = msg.Format(language.English, Args{})
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given invalid ICU MessageFormat patterns", t, func() {
+		patterns := []string{
+			"Hello {name",
+			"Hello name}",
+			"{count, plural, one {# file}}",
+			"{count, plural, single {# file} other {# files}}",
+			"{gender, select, male {He}}",
+			"{count, unknown}",
+			"{}",
+		}
+
+		Convey("Then parsing should return a syntax error", func() {
+			for _, pattern := range patterns {
+				_, err := ParseMessage(pattern)
+				So(err, ShouldHaveSameTypeAs, &SyntaxError{})
+			}
+		})
+	})
+}
@@ -0,0 +1,178 @@
+package i18n
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/context"
+	"golang.org/x/text/language"
+)
+
+// Metadata describes a translation for translators and tools, it does not
+// change how the translation is looked up
+type Metadata struct {
+	// Description explains where and how the translation is used
+	Description string
+
+	// Context disambiguates translations with the same source text, as
+	// msgctxt does in gettext. Keys stay unique without it.
+	Context string
+
+	// MaxLength is the most characters the value and each plural variant may
+	// have, 0 means no limit. It is checked when translations are added.
+	MaxLength int
+
+	// Labels group translations, for example by the surface they appear on
+	Labels []string
+
+	// Created and Updated are set when the translation is added, Author
+	// should be set to whoever made the change
+	Created time.Time
+	Updated time.Time
+	Author  string
+
+	// ReviewedBy and Reviewed record who last approved or rejected the
+	// translation and when
+	ReviewedBy string
+	Reviewed   time.Time
+}
+
+// HasLabel checks whether the metadata has a label
+func (metadata *Metadata) HasLabel(label string) bool {
+	if metadata == nil {
+		return false
+	}
+
+	for _, l := range metadata.Labels {
+		if l == label {
+			return true
+		}
+	}
+
+	return false
+}
+
+// copy makes a deep copy of the metadata
+func (metadata *Metadata) copy() *Metadata {
+	if metadata == nil {
+		return nil
+	}
+
+	m := *metadata
+	m.Labels = append([]string(nil), metadata.Labels...)
+	return &m
+}
+
+// LengthError is returned when adding a translation longer than the
+// MaxLength in its metadata
+type LengthError struct {
+	Translation *Translation
+	Length      int
+	MaxLength   int
+}
+
+func (err *LengthError) Error() string {
+	return fmt.Sprintf("i18n: %s in %s is %d characters, at most %d are allowed", err.Translation.Key, err.Translation.Lang, err.Length, err.MaxLength)
+}
+
+// validate checks a translation against the constraints in its metadata
+func (translation *Translation) validate() error {
+	if translation.Metadata == nil || translation.Metadata.MaxLength <= 0 {
+		return nil
+	}
+
+	length := utf8.RuneCountInString(translation.Value)
+	for _, value := range translation.Plurals {
+		if n := utf8.RuneCountInString(value); n > length {
+			length = n
+		}
+	}
+
+	if length > translation.Metadata.MaxLength {
+		return &LengthError{
+			Translation: translation,
+			Length:      length,
+			MaxLength:   translation.Metadata.MaxLength,
+		}
+	}
+
+	return nil
+}
+
+// unloaded reads the current versions of translations in languages that are
+// not loaded in lazy mode, so stamp can keep what they had. It reads storage
+// and is called before the lock is taken.
+func (i18n *I18n) unloaded(ctx context.Context, translations []*Translation) (map[string]*Translation, error) {
+	c := i18n.load()
+	if !c.lazy.enabled {
+		return nil, nil
+	}
+
+	var tags []language.Tag
+	for _, translation := range translations {
+		if _, ok := c.loaded[translation.Lang.String()]; !ok {
+			tags = appendTag(tags, translation.Lang)
+		}
+	}
+
+	stored, _, err := i18n.getLanguages(ctx, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[string]*Translation, len(stored))
+	for _, translation := range stored {
+		current[batchKey(translation)] = translation
+	}
+
+	return current, nil
+}
+
+// stamp gets copies of translations with the timestamps of their metadata
+// set, the translations given are not changed. The created time is kept from
+// the current version of a translation, and a translation without metadata
+// keeps the metadata of the current version. A translation that is not
+// approved keeps the approved version of the current one in Approved. The
+// current version is taken from the catalog, or from the versions read by
+// unloaded for languages that are not loaded.
+func (i18n *I18n) stamp(translations []*Translation, current map[string]*Translation, now time.Time) []*Translation {
+	c := i18n.load()
+
+	stamped := make([]*Translation, len(translations))
+
+	for i, translation := range translations {
+		previous := current[batchKey(translation)]
+		if entry := c.entry(translation.Lang, translation.Key); entry != nil {
+			previous = entry.translation
+		}
+
+		t := *translation
+		t.Metadata = translation.Metadata.copy()
+		if t.Metadata == nil && previous != nil {
+			t.Metadata = previous.Metadata.copy()
+		}
+
+		if t.Metadata != nil {
+			if t.Metadata.Created.IsZero() {
+				t.Metadata.Created = now
+				if previous != nil && previous.Metadata != nil && !previous.Metadata.Created.IsZero() {
+					t.Metadata.Created = previous.Metadata.Created
+				}
+			}
+
+			t.Metadata.Updated = now
+		}
+
+		switch {
+		case t.state() == StateApproved:
+			t.Approved = nil
+		case t.Approved == nil && previous != nil:
+			t.Approved = previous.approvedVersion()
+		}
+
+		stamped[i] = &t
+	}
+
+	return stamped
+}
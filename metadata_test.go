@@ -0,0 +1,182 @@
+package i18n
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/text/language"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMetadata(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given an i18n instance", t, func() {
+		storage := NewInMemoryStorage()
+		i18n := New(storage)
+
+		Convey("When a translation with metadata is added", func() {
+			translation := &Translation{
+				Lang:  language.English,
+				Key:   "checkout.pay",
+				Value: "Pay",
+				Metadata: &Metadata{
+					Description: "Button that completes the order",
+					Context:     "verb",
+					Labels:      []string{"web"},
+					Author:      "alice",
+				},
+			}
+
+			before := time.Now()
+			So(i18n.Add(translation), ShouldBeNil)
+
+			Convey("Then the timestamps should be set", func() {
+				metadata := i18n.Get(language.English, "checkout.pay").Metadata
+				So(metadata.Created, ShouldHappenOnOrAfter, before)
+				So(metadata.Updated, ShouldEqual, metadata.Created)
+				So(metadata.Author, ShouldEqual, "alice")
+				So(metadata.HasLabel("web"), ShouldBeTrue)
+				So(metadata.HasLabel("mobile"), ShouldBeFalse)
+			})
+
+			Convey("Then it should be stored", func() {
+				results, err := storage.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Metadata, ShouldResemble, i18n.Get(language.English, "checkout.pay").Metadata)
+			})
+
+			Convey("Then the metadata given should not be changed", func() {
+				So(translation.Metadata.Created.IsZero(), ShouldBeTrue)
+				So(translation.Metadata.Updated.IsZero(), ShouldBeTrue)
+			})
+
+			Convey("Then updating it should keep the created time", func() {
+				created := i18n.Get(language.English, "checkout.pay").Metadata.Created
+
+				So(i18n.Add(&Translation{
+					Lang:     language.English,
+					Key:      "checkout.pay",
+					Value:    "Pay now",
+					Metadata: &Metadata{Author: "bob"},
+				}), ShouldBeNil)
+
+				metadata := i18n.Get(language.English, "checkout.pay").Metadata
+				So(metadata.Created, ShouldEqual, created)
+				So(metadata.Updated, ShouldHappenOnOrAfter, created)
+				So(metadata.Author, ShouldEqual, "bob")
+			})
+
+			Convey("Then updating it without metadata should keep the metadata", func() {
+				So(i18n.Add(&Translation{
+					Lang:  language.English,
+					Key:   "checkout.pay",
+					Value: "Pay now",
+				}), ShouldBeNil)
+
+				metadata := i18n.Get(language.English, "checkout.pay").Metadata
+				So(metadata, ShouldNotBeNil)
+				So(metadata.Description, ShouldEqual, "Button that completes the order")
+				So(metadata.Author, ShouldEqual, "alice")
+			})
+
+			Convey("Then copying its group should copy the metadata", func() {
+				So(i18n.Group("checkout").CopyTo(i18n.Group("cart")), ShouldBeNil)
+
+				copied := i18n.Get(language.English, "cart.pay")
+				So(copied.Metadata.Description, ShouldEqual, translation.Metadata.Description)
+				So(copied.Metadata, ShouldNotPointTo, translation.Metadata)
+			})
+		})
+
+		Convey("When a translation in a language that is not loaded is updated", func() {
+			So(i18n.Add(&Translation{
+				Lang:     language.French,
+				Key:      "checkout.pay",
+				Value:    "Payer",
+				Metadata: &Metadata{Author: "alice"},
+			}), ShouldBeNil)
+			created := i18n.Get(language.French, "checkout.pay").Metadata.Created
+
+			lazy := New(storage)
+			lazy.SetLazy(true)
+
+			So(lazy.Add(&Translation{
+				Lang:     language.French,
+				Key:      "checkout.pay",
+				Value:    "Payer maintenant",
+				Metadata: &Metadata{Author: "bob"},
+			}), ShouldBeNil)
+
+			Convey("Then the created time should be kept", func() {
+				So(lazy.Get(language.French, "checkout.pay").Metadata.Created, ShouldEqual, created)
+			})
+		})
+
+		Convey("When a translation longer than its max length is added", func() {
+			err := i18n.Add(&Translation{
+				Lang:  language.German,
+				Key:   "checkout.pay",
+				Value: "Bezahlen",
+				Plurals: map[PluralForm]string{
+					PluralOther: "Jetzt bezahlen",
+				},
+				Metadata: &Metadata{MaxLength: 10},
+			})
+
+			Convey("Then a length error should be returned", func() {
+				var lengthErr *LengthError
+				So(errors.As(err, &lengthErr), ShouldBeTrue)
+				So(lengthErr.Length, ShouldEqual, 14)
+				So(lengthErr.MaxLength, ShouldEqual, 10)
+			})
+
+			Convey("Then nothing should be stored", func() {
+				results, err := storage.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When many translations are added and one is too long", func() {
+			err := i18n.AddMany([]*Translation{
+				{Lang: language.English, Key: "a", Value: "fine"},
+				{Lang: language.English, Key: "b", Value: "too long", Metadata: &Metadata{MaxLength: 3}},
+			})
+
+			Convey("Then nothing should be stored", func() {
+				So(err, ShouldHaveSameTypeAs, &LengthError{})
+
+				results, err := storage.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given a lazy i18n instance whose storage hangs", t, func() {
+		storage := &hookedStorage{Storage: NewInMemoryStorage(), hook: func() {}}
+		lazy := New(storage)
+		lazy.SetLazy(true)
+		storage.GetLanguage(language.French)
+		storage.release = make(chan struct{})
+
+		Convey("When a translation in a language that is not loaded is added with a deadline", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			err := lazy.AddContext(ctx, &Translation{Lang: language.French, Key: "hello", Value: "Bonjour"})
+
+			Convey("Then reading the current version should give up without holding the lock", func() {
+				So(err, ShouldEqual, context.DeadlineExceeded)
+
+				lazy.SetLanguageLimit(1)
+				close(storage.release)
+			})
+		})
+	})
+}
@@ -0,0 +1,213 @@
+package i18n
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/net/context"
+)
+
+// Metrics is an Observer that counts events in memory. It is a http.Handler
+// that exposes the counts in the Prometheus text format. The zero value is
+// ready to use.
+type Metrics struct {
+	hits      uint64
+	fallbacks uint64
+	misses    uint64
+
+	lock sync.Mutex
+
+	syncs            uint64
+	syncErrors       uint64
+	syncSeconds      float64
+	syncTranslations int
+
+	matches map[matchLabels]uint64
+	storage map[storageLabels]*storageCounters
+}
+
+type matchLabels struct {
+	lang     string
+	redirect bool
+}
+
+type storageLabels struct {
+	backend string
+	op      string
+}
+
+type storageCounters struct {
+	ops     uint64
+	errors  uint64
+	seconds float64
+	bytes   uint64
+}
+
+// NewMetrics creates an empty set of counters
+func NewMetrics() *Metrics {
+	return &Metrics{
+		matches: make(map[matchLabels]uint64),
+		storage: make(map[storageLabels]*storageCounters),
+	}
+}
+
+// ObserveLookup counts lookups answered by the requested language, answered
+// further down the fallback chain and missed
+func (metrics *Metrics) ObserveLookup(ctx context.Context, result *LookupResult) {
+	switch {
+	case !result.Found:
+		atomic.AddUint64(&metrics.misses, 1)
+	case result.Depth > 0:
+		atomic.AddUint64(&metrics.fallbacks, 1)
+	default:
+		atomic.AddUint64(&metrics.hits, 1)
+	}
+}
+
+// ObserveSync counts syncs, failures and their duration, and records the
+// number of translations loaded by the last sync that replaced the catalog
+func (metrics *Metrics) ObserveSync(ctx context.Context, event *SyncEvent) {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+
+	metrics.syncs++
+	metrics.syncSeconds += event.Duration.Seconds()
+
+	if event.Err != nil {
+		metrics.syncErrors++
+	}
+
+	if event.Err == nil || event.Translations > 0 {
+		metrics.syncTranslations = event.Translations
+	}
+}
+
+// ObserveMatch counts matched languages and redirects
+func (metrics *Metrics) ObserveMatch(ctx context.Context, event *MatchEvent) {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+
+	if metrics.matches == nil {
+		metrics.matches = make(map[matchLabels]uint64)
+	}
+
+	metrics.matches[matchLabels{event.Tag.String(), event.Redirect}]++
+}
+
+// ObserveStorage counts storage operations, failures, their duration and
+// payload size by backend and operation
+func (metrics *Metrics) ObserveStorage(ctx context.Context, event *StorageEvent) {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+
+	if metrics.storage == nil {
+		metrics.storage = make(map[storageLabels]*storageCounters)
+	}
+
+	labels := storageLabels{event.Backend, event.Op}
+
+	counters, ok := metrics.storage[labels]
+	if !ok {
+		counters = new(storageCounters)
+		metrics.storage[labels] = counters
+	}
+
+	counters.ops++
+	counters.seconds += event.Duration.Seconds()
+	counters.bytes += uint64(event.Bytes)
+
+	if event.Err != nil {
+		counters.errors++
+	}
+}
+
+// ServeHTTP writes the counts in the Prometheus text exposition format
+func (metrics *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	buf := bufio.NewWriter(w)
+	defer buf.Flush()
+
+	metrics.write(buf)
+}
+
+func (metrics *Metrics) write(w *bufio.Writer) {
+	header(w, "i18n_lookups_total", "counter", "Translation lookups by result.")
+	fmt.Fprintf(w, "i18n_lookups_total{result=\"hit\"} %d\n", atomic.LoadUint64(&metrics.hits))
+	fmt.Fprintf(w, "i18n_lookups_total{result=\"fallback\"} %d\n", atomic.LoadUint64(&metrics.fallbacks))
+	fmt.Fprintf(w, "i18n_lookups_total{result=\"miss\"} %d\n", atomic.LoadUint64(&metrics.misses))
+
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+
+	header(w, "i18n_syncs_total", "counter", "Syncs completed, whether or not they failed.")
+	fmt.Fprintf(w, "i18n_syncs_total %d\n", metrics.syncs)
+
+	header(w, "i18n_sync_errors_total", "counter", "Syncs that failed.")
+	fmt.Fprintf(w, "i18n_sync_errors_total %d\n", metrics.syncErrors)
+
+	header(w, "i18n_sync_duration_seconds", "summary", "Time taken by syncs.")
+	fmt.Fprintf(w, "i18n_sync_duration_seconds_sum %g\n", metrics.syncSeconds)
+	fmt.Fprintf(w, "i18n_sync_duration_seconds_count %d\n", metrics.syncs)
+
+	header(w, "i18n_sync_translations", "gauge", "Translations loaded by the last sync that replaced the catalog.")
+	fmt.Fprintf(w, "i18n_sync_translations %d\n", metrics.syncTranslations)
+
+	matches := make([]matchLabels, 0, len(metrics.matches))
+	for labels := range metrics.matches {
+		matches = append(matches, labels)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].lang != matches[j].lang {
+			return matches[i].lang < matches[j].lang
+		}
+		return !matches[i].redirect && matches[j].redirect
+	})
+
+	header(w, "i18n_matches_total", "counter", "Request languages matched, by language and whether the request was redirected.")
+	for _, labels := range matches {
+		fmt.Fprintf(w, "i18n_matches_total{language=%q,redirect=\"%t\"} %d\n", labels.lang, labels.redirect, metrics.matches[labels])
+	}
+
+	storage := make([]storageLabels, 0, len(metrics.storage))
+	for labels := range metrics.storage {
+		storage = append(storage, labels)
+	}
+	sort.Slice(storage, func(i, j int) bool {
+		if storage[i].backend != storage[j].backend {
+			return storage[i].backend < storage[j].backend
+		}
+		return storage[i].op < storage[j].op
+	})
+
+	header(w, "i18n_storage_operations_total", "counter", "Storage operations by backend and operation.")
+	for _, labels := range storage {
+		fmt.Fprintf(w, "i18n_storage_operations_total{backend=%q,op=%q} %d\n", labels.backend, labels.op, metrics.storage[labels].ops)
+	}
+
+	header(w, "i18n_storage_errors_total", "counter", "Storage operations that failed.")
+	for _, labels := range storage {
+		fmt.Fprintf(w, "i18n_storage_errors_total{backend=%q,op=%q} %d\n", labels.backend, labels.op, metrics.storage[labels].errors)
+	}
+
+	header(w, "i18n_storage_duration_seconds", "summary", "Time taken by storage operations.")
+	for _, labels := range storage {
+		counters := metrics.storage[labels]
+		fmt.Fprintf(w, "i18n_storage_duration_seconds_sum{backend=%q,op=%q} %g\n", labels.backend, labels.op, counters.seconds)
+		fmt.Fprintf(w, "i18n_storage_duration_seconds_count{backend=%q,op=%q} %d\n", labels.backend, labels.op, counters.ops)
+	}
+
+	header(w, "i18n_storage_bytes_total", "counter", "Payload bytes sent or received by storage operations.")
+	for _, labels := range storage {
+		fmt.Fprintf(w, "i18n_storage_bytes_total{backend=%q,op=%q} %d\n", labels.backend, labels.op, metrics.storage[labels].bytes)
+	}
+}
+
+func header(w *bufio.Writer, name string, kind string, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
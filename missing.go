@@ -0,0 +1,75 @@
+package i18n
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+	"golang.org/x/text/language"
+)
+
+// MissingKeyHandler is called when a translation is missing, the value it
+// returns is used in place of the translation
+type MissingKeyHandler func(ctx context.Context, i18n *I18n, lang language.Tag, key string) string
+
+// MissingKeyEmpty returns an empty string, this is the default policy
+func MissingKeyEmpty(ctx context.Context, i18n *I18n, lang language.Tag, key string) string {
+	return ""
+}
+
+// MissingKeyReturnKey returns the key itself
+func MissingKeyReturnKey(ctx context.Context, i18n *I18n, lang language.Tag, key string) string {
+	return key
+}
+
+// MissingKeyMarker returns a visible [[missing:key]] marker
+func MissingKeyMarker(ctx context.Context, i18n *I18n, lang language.Tag, key string) string {
+	return fmt.Sprintf("[[missing:%s]]", key)
+}
+
+// MissingKeyDefaultLanguage returns the value in the default language, or an
+// empty string if that is missing too. Drafts are returned if the context is
+// in preview mode.
+func MissingKeyDefaultLanguage(ctx context.Context, i18n *I18n, lang language.Tag, key string) string {
+	if translation := i18n.GetContext(ctx, i18n.GetDefaultLanguage(), key); translation != nil {
+		return translation.Value
+	}
+	return ""
+}
+
+// MissingKeyPanic panics, this is intended for use in tests
+func MissingKeyPanic(ctx context.Context, i18n *I18n, lang language.Tag, key string) string {
+	panic(fmt.Sprintf("i18n: missing translation %q in %s", key, lang.String()))
+}
+
+// SetMissingKeyHandler sets the policy used when a translation is missing, nil
+// restores the default of returning an empty string
+func (i18n *I18n) SetMissingKeyHandler(handler MissingKeyHandler) {
+	i18n.lock.Lock()
+	defer i18n.lock.Unlock()
+
+	next := i18n.load().clone()
+	next.missingKeyHandler = handler
+	i18n.catalog.Store(next)
+}
+
+func (i18n *I18n) missing(ctx context.Context, lang interface{}, key string) string {
+	handler := i18n.load().missingKeyHandler
+	if options := GetOptionsFromContext(ctx); options != nil && options.MissingKeyHandler != nil {
+		handler = options.MissingKeyHandler
+	}
+
+	if handler == nil {
+		handler = MissingKeyEmpty
+	}
+
+	var tag language.Tag
+
+	switch lang.(type) {
+	case string:
+		tag, _ = language.Parse(lang.(string))
+	case language.Tag:
+		tag = lang.(language.Tag)
+	}
+
+	return handler(ctx, i18n, tag, key)
+}
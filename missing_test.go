@@ -0,0 +1,98 @@
+package i18n
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"golang.org/x/text/language"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMissingKey(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a translation missing in the requested language", t, func() {
+		i18n := New()
+		i18n.SetDefaultLanguage(language.English)
+		i18n.Add(&Translation{
+			Lang:  language.English,
+			Key:   "SomeGroup.SomeKey",
+			Value: "SomeValue",
+		})
+		group := i18n.Group("SomeGroup")
+
+		Convey("When no handler is set", func() {
+			Convey("Then an empty string should be returned", func() {
+				So(i18n.T(language.Spanish, "SomeGroup.SomeKey"), ShouldEqual, "")
+			})
+		})
+
+		Convey("When the key is returned", func() {
+			i18n.SetMissingKeyHandler(MissingKeyReturnKey)
+
+			Convey("Then the key should be returned", func() {
+				So(i18n.T(language.Spanish, "SomeGroup.SomeKey"), ShouldEqual, "SomeGroup.SomeKey")
+				So(group.T(language.Spanish, "SomeKey"), ShouldEqual, "SomeGroup.SomeKey")
+			})
+		})
+
+		Convey("When a marker is returned", func() {
+			i18n.SetMissingKeyHandler(MissingKeyMarker)
+
+			Convey("Then the marker should be returned by every method", func() {
+				So(i18n.T(language.Spanish, "SomeGroup.SomeKey"), ShouldEqual, "[[missing:SomeGroup.SomeKey]]")
+				So(i18n.Tf("es", "SomeGroup.SomeKey"), ShouldEqual, "[[missing:SomeGroup.SomeKey]]")
+				So(i18n.Plural(language.Spanish, "SomeGroup.SomeKey", 1), ShouldEqual, "[[missing:SomeGroup.SomeKey]]")
+				So(group.GenerateHelper(language.Spanish)("SomeKey"), ShouldEqual, "[[missing:SomeGroup.SomeKey]]")
+				So(i18n.GenerateFormatHelper(language.Spanish)("SomeGroup.SomeKey"), ShouldEqual, "[[missing:SomeGroup.SomeKey]]")
+
+				result, err := group.Format(language.Spanish, "SomeKey")
+				So(err, ShouldBeNil)
+				So(result, ShouldEqual, "[[missing:SomeGroup.SomeKey]]")
+			})
+		})
+
+		Convey("When the default language is returned", func() {
+			i18n.SetMissingKeyHandler(MissingKeyDefaultLanguage)
+
+			Convey("Then the default language value should be returned", func() {
+				So(i18n.T(language.Spanish, "SomeGroup.SomeKey"), ShouldEqual, "SomeValue")
+				So(i18n.T(language.Spanish, "OtherKey"), ShouldEqual, "")
+			})
+
+			Convey("Then a draft in the default language should be returned in preview mode", func() {
+				So(i18n.Add(&Translation{Lang: language.English, Key: "DraftKey", Value: "DraftValue", State: StateDraft}), ShouldBeNil)
+
+				ctx := NewPreviewContext(NewLanguageContext(context.Background(), language.Spanish))
+				So(i18n.TCtx(ctx, "DraftKey"), ShouldEqual, "DraftValue")
+				So(i18n.T(language.Spanish, "DraftKey"), ShouldEqual, "")
+			})
+		})
+
+		Convey("When missing keys panic", func() {
+			i18n.SetMissingKeyHandler(MissingKeyPanic)
+
+			Convey("Then a missing key should panic", func() {
+				So(func() { i18n.T(language.Spanish, "SomeGroup.SomeKey") }, ShouldPanic)
+				So(func() { i18n.T(language.English, "SomeGroup.SomeKey") }, ShouldNotPanic)
+			})
+		})
+
+		Convey("When a custom handler is set", func() {
+			var calledLang language.Tag
+			var calledKey string
+
+			i18n.SetMissingKeyHandler(func(ctx context.Context, i18n *I18n, lang language.Tag, key string) string {
+				calledLang, calledKey = lang, key
+				return "custom"
+			})
+
+			Convey("Then it should be called with the language and key", func() {
+				So(i18n.T("es", "SomeGroup.SomeKey"), ShouldEqual, "custom")
+				So(calledLang, ShouldResemble, language.Spanish)
+				So(calledKey, ShouldEqual, "SomeGroup.SomeKey")
+			})
+		})
+	})
+}
@@ -0,0 +1,130 @@
+package i18n
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/text/language"
+)
+
+// SyncEvent describes a completed sync
+type SyncEvent struct {
+	Start    time.Time
+	Duration time.Duration
+
+	// Translations is the number of translations loaded, it is 0 if the
+	// catalog was not replaced
+	Translations int
+
+	// Skipped is the number of ICU translations left out of the catalog as
+	// they could not be parsed, the sync still succeeds
+	Skipped int
+	Err     error
+}
+
+// MatchEvent describes the language matched for a http request
+type MatchEvent struct {
+	Tag language.Tag
+
+	// Redirect is set if the request was redirected to the matched language
+	Redirect bool
+}
+
+// StorageEvent describes an operation made by a storage against its backend
+type StorageEvent struct {
+	Backend string
+	Op      string
+
+	Start    time.Time
+	Duration time.Duration
+
+	// Bytes is the size of the payload sent or received
+	Bytes int
+	Err   error
+}
+
+// Observer is notified of lookups, syncs, language matches and storage
+// operations. The context is that of the operation where there is one, so
+// an Observer can also be used to record traces. Observers are called inline
+// and must be safe for concurrent use.
+type Observer interface {
+	ObserveLookup(ctx context.Context, result *LookupResult)
+	ObserveSync(ctx context.Context, event *SyncEvent)
+	ObserveMatch(ctx context.Context, event *MatchEvent)
+	ObserveStorage(ctx context.Context, event *StorageEvent)
+}
+
+type observers []Observer
+
+// Observers combines observers into one that notifies each of them in turn
+func Observers(o ...Observer) Observer {
+	return observers(o)
+}
+
+func (o observers) ObserveLookup(ctx context.Context, result *LookupResult) {
+	for _, observer := range o {
+		observer.ObserveLookup(ctx, result)
+	}
+}
+
+func (o observers) ObserveSync(ctx context.Context, event *SyncEvent) {
+	for _, observer := range o {
+		observer.ObserveSync(ctx, event)
+	}
+}
+
+func (o observers) ObserveMatch(ctx context.Context, event *MatchEvent) {
+	for _, observer := range o {
+		observer.ObserveMatch(ctx, event)
+	}
+}
+
+func (o observers) ObserveStorage(ctx context.Context, event *StorageEvent) {
+	for _, observer := range o {
+		observer.ObserveStorage(ctx, event)
+	}
+}
+
+// SetObserver sets the observer notified of lookups, syncs and matches made by
+// Matchers using this I18n, nil disables observation. Storages are observed
+// separately.
+func (i18n *I18n) SetObserver(observer Observer) {
+	i18n.lock.Lock()
+	defer i18n.lock.Unlock()
+
+	next := i18n.load().clone()
+	next.observer = observer
+	i18n.catalog.Store(next)
+}
+
+// observeSync reports a sync to the observer if one is set
+func (i18n *I18n) observeSync(ctx context.Context, start time.Time, translations int, skipped int, err error) {
+	if observer := i18n.load().observer; observer != nil {
+		observer.ObserveSync(ctx, &SyncEvent{
+			Start:        start,
+			Duration:     time.Since(start),
+			Translations: translations,
+			Skipped:      skipped,
+			Err:          err,
+		})
+	}
+}
+
+// newLookupResult describes the outcome of a catalog lookup
+func newLookupResult(lang language.Tag, key string, entry *cacheEntry, resolved language.Tag, depth int) *LookupResult {
+	result := &LookupResult{
+		Key:       key,
+		Requested: lang,
+		Resolved:  language.Und,
+	}
+
+	if entry != nil {
+		result.Translation = entry.translation
+		result.Value = entry.translation.Value
+		result.Resolved = resolved
+		result.Depth = depth
+		result.Found = true
+	}
+
+	return result
+}
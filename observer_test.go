@@ -0,0 +1,163 @@
+package i18n
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+	"golang.org/x/text/language"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// recordingObserver keeps every event it is notified of
+type recordingObserver struct {
+	lock    sync.Mutex
+	lookups []*LookupResult
+	syncs   []*SyncEvent
+	matches []*MatchEvent
+}
+
+func (o *recordingObserver) ObserveLookup(ctx context.Context, result *LookupResult) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.lookups = append(o.lookups, result)
+}
+
+func (o *recordingObserver) ObserveSync(ctx context.Context, event *SyncEvent) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.syncs = append(o.syncs, event)
+}
+
+func (o *recordingObserver) ObserveMatch(ctx context.Context, event *MatchEvent) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.matches = append(o.matches, event)
+}
+
+func (o *recordingObserver) ObserveStorage(ctx context.Context, event *StorageEvent) {}
+
+func scrape(metrics *Metrics) string {
+	w := httptest.NewRecorder()
+	metrics.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := ioutil.ReadAll(w.Body)
+	return string(body)
+}
+
+func TestObserver(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given an observed I18n", t, func() {
+		metrics := NewMetrics()
+		recorder := &recordingObserver{}
+
+		i18n := New()
+		i18n.SetObserver(Observers(metrics, recorder))
+		i18n.SetDefaultLanguage(language.English)
+		i18n.AddSupportedLanguage(language.BritishEnglish)
+		i18n.Add(&Translation{
+			Lang:  language.English,
+			Key:   "SomeKey",
+			Value: "SomeValue",
+		})
+		i18n.Add(&Translation{
+			Lang:  language.BritishEnglish,
+			Key:   "OtherKey",
+			Value: "OtherValue",
+		})
+
+		Convey("When translations are looked up", func() {
+			i18n.Get(language.English, "SomeKey")
+			i18n.T(language.BritishEnglish, "SomeKey")
+			i18n.T(language.BritishEnglish, "OtherKey")
+			i18n.TCtx(NewLanguageContext(context.Background(), language.English), "MissingKey")
+
+			Convey("Then hits, fallbacks and misses should be counted", func() {
+				body := scrape(metrics)
+				So(body, ShouldContainSubstring, "i18n_lookups_total{result=\"hit\"} 2\n")
+				So(body, ShouldContainSubstring, "i18n_lookups_total{result=\"fallback\"} 1\n")
+				So(body, ShouldContainSubstring, "i18n_lookups_total{result=\"miss\"} 1\n")
+			})
+
+			Convey("Then the observer should see the resolved language", func() {
+				So(recorder.lookups, ShouldHaveLength, 4)
+				So(recorder.lookups[1].Resolved, ShouldResemble, language.English)
+				So(recorder.lookups[1].Depth, ShouldBeGreaterThan, 0)
+			})
+		})
+
+		Convey("When the I18n is synced", func() {
+			So(i18n.Sync(), ShouldBeNil)
+
+			Convey("Then the sync should be observed", func() {
+				So(recorder.syncs, ShouldHaveLength, 1)
+				So(recorder.syncs[0].Translations, ShouldEqual, 2)
+				So(recorder.syncs[0].Err, ShouldBeNil)
+
+				body := scrape(metrics)
+				So(body, ShouldContainSubstring, "i18n_syncs_total 1\n")
+				So(body, ShouldContainSubstring, "i18n_sync_errors_total 0\n")
+				So(body, ShouldContainSubstring, "i18n_sync_translations 2\n")
+			})
+
+			Convey("Then the observer should still be set", func() {
+				i18n.Get(language.English, "SomeKey")
+				So(recorder.lookups, ShouldHaveLength, 1)
+			})
+		})
+
+		Convey("When requests are matched", func() {
+			server := httptest.NewServer(NewMatcher(i18n).Wrapper(nil))
+			defer server.Close()
+
+			http.Get(server.URL + "/en/page")
+			http.Get(server.URL + "/es/page")
+
+			Convey("Then matches and redirects should be counted", func() {
+				body := scrape(metrics)
+				So(body, ShouldContainSubstring, "i18n_matches_total{language=\"en\",redirect=\"false\"} 2\n")
+				So(body, ShouldContainSubstring, "i18n_matches_total{language=\"en\",redirect=\"true\"} 1\n")
+			})
+		})
+
+		Convey("When storage operations are observed", func() {
+			metrics.ObserveStorage(context.Background(), &StorageEvent{
+				Backend: "redis",
+				Op:      "GetAll",
+				Bytes:   12,
+			})
+			metrics.ObserveStorage(context.Background(), &StorageEvent{
+				Backend: "redis",
+				Op:      "GetAll",
+				Err:     errFailing,
+			})
+
+			Convey("Then operations, errors and bytes should be counted", func() {
+				body := scrape(metrics)
+				So(body, ShouldContainSubstring, "i18n_storage_operations_total{backend=\"redis\",op=\"GetAll\"} 2\n")
+				So(body, ShouldContainSubstring, "i18n_storage_errors_total{backend=\"redis\",op=\"GetAll\"} 1\n")
+				So(body, ShouldContainSubstring, "i18n_storage_bytes_total{backend=\"redis\",op=\"GetAll\"} 12\n")
+				So(body, ShouldContainSubstring, "# TYPE i18n_storage_duration_seconds summary\n")
+			})
+		})
+	})
+
+	Convey("Given zero value metrics", t, func() {
+		metrics := new(Metrics)
+
+		Convey("When matches and storage operations are observed", func() {
+			metrics.ObserveMatch(context.Background(), &MatchEvent{Tag: language.English})
+			metrics.ObserveStorage(context.Background(), &StorageEvent{Backend: "redis", Op: "GetAll"})
+
+			Convey("Then they should be counted", func() {
+				body := scrape(metrics)
+				So(body, ShouldContainSubstring, "i18n_matches_total{language=\"en\",redirect=\"false\"} 1\n")
+				So(body, ShouldContainSubstring, "i18n_storage_operations_total{backend=\"redis\",op=\"GetAll\"} 1\n")
+			})
+		})
+	})
+}
@@ -0,0 +1,54 @@
+package i18n
+
+import (
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+// PluralForm is a CLDR plural category
+type PluralForm string
+
+// CLDR plural categories
+const (
+	PluralZero  PluralForm = "zero"
+	PluralOne   PluralForm = "one"
+	PluralTwo   PluralForm = "two"
+	PluralFew   PluralForm = "few"
+	PluralMany  PluralForm = "many"
+	PluralOther PluralForm = "other"
+)
+
+var pluralForms = map[plural.Form]PluralForm{
+	plural.Zero:  PluralZero,
+	plural.One:   PluralOne,
+	plural.Two:   PluralTwo,
+	plural.Few:   PluralFew,
+	plural.Many:  PluralMany,
+	plural.Other: PluralOther,
+}
+
+// GetPluralForm gets the CLDR plural category of n in the given language
+func GetPluralForm(lang language.Tag, n int) PluralForm {
+	if n < 0 {
+		n = -n
+	}
+
+	form := plural.Cardinal.MatchPlural(lang, n, 0, 0, 0, 0)
+	return pluralForms[form]
+}
+
+// Plural gets the variant of the translation for n, the "other" variant is
+// used if there is no variant for the category and Value if that is missing
+func (translation *Translation) Plural(n int) string {
+	form := GetPluralForm(translation.Lang, n)
+
+	if value, ok := translation.Plurals[form]; ok {
+		return value
+	}
+
+	if value, ok := translation.Plurals[PluralOther]; ok {
+		return value
+	}
+
+	return translation.Value
+}
@@ -0,0 +1,74 @@
+package i18n
+
+import (
+	"testing"
+
+	"golang.org/x/text/language"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPlural(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given the CLDR plural rules", t, func() {
+		Convey("Then english should only have one and other", func() {
+			So(GetPluralForm(language.English, 0), ShouldEqual, PluralOther)
+			So(GetPluralForm(language.English, 1), ShouldEqual, PluralOne)
+			So(GetPluralForm(language.English, 5), ShouldEqual, PluralOther)
+		})
+
+		Convey("Then polish should have one, few and many", func() {
+			So(GetPluralForm(language.Polish, 1), ShouldEqual, PluralOne)
+			So(GetPluralForm(language.Polish, 3), ShouldEqual, PluralFew)
+			So(GetPluralForm(language.Polish, 5), ShouldEqual, PluralMany)
+			So(GetPluralForm(language.Polish, 22), ShouldEqual, PluralFew)
+		})
+
+		Convey("Then arabic should have all six categories", func() {
+			So(GetPluralForm(language.Arabic, 0), ShouldEqual, PluralZero)
+			So(GetPluralForm(language.Arabic, 1), ShouldEqual, PluralOne)
+			So(GetPluralForm(language.Arabic, 2), ShouldEqual, PluralTwo)
+			So(GetPluralForm(language.Arabic, 3), ShouldEqual, PluralFew)
+			So(GetPluralForm(language.Arabic, 11), ShouldEqual, PluralMany)
+			So(GetPluralForm(language.Arabic, 100), ShouldEqual, PluralOther)
+		})
+
+		Convey("Then negative numbers should use the absolute value", func() {
+			So(GetPluralForm(language.English, -1), ShouldEqual, PluralOne)
+		})
+	})
+
+	Convey("Given a translation with plural variants", t, func() {
+		translation := &Translation{
+			Lang:  language.English,
+			Key:   "SomeKey",
+			Value: "SomeValue",
+			Plurals: map[PluralForm]string{
+				PluralOne:   "1 item",
+				PluralOther: "many items",
+			},
+		}
+
+		Convey("Then the matching variant should be selected", func() {
+			So(translation.Plural(1), ShouldEqual, "1 item")
+			So(translation.Plural(5), ShouldEqual, "many items")
+		})
+
+		Convey("When the matching variant is missing", func() {
+			delete(translation.Plurals, PluralOne)
+
+			Convey("Then the other variant should be used", func() {
+				So(translation.Plural(1), ShouldEqual, "many items")
+			})
+		})
+
+		Convey("When there are no variants", func() {
+			translation.Plurals = nil
+
+			Convey("Then the value should be used", func() {
+				So(translation.Plural(1), ShouldEqual, "SomeValue")
+			})
+		})
+	})
+}
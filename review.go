@@ -0,0 +1,270 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/text/language"
+)
+
+// State is where a translation is in the review workflow
+type State string
+
+// Review workflow states, translations without a state are approved so
+// translations stored before states existed are still served
+const (
+	StateDraft       State = "draft"
+	StateNeedsReview State = "needs-review"
+	StateApproved    State = "approved"
+	StateRejected    State = "rejected"
+)
+
+// transitions lists the states each state can be moved to by the review
+// methods, Add can set any state
+var transitions = map[State][]State{
+	StateDraft:       {StateNeedsReview},
+	StateNeedsReview: {StateApproved, StateRejected, StateDraft},
+	StateRejected:    {StateDraft},
+}
+
+var (
+	// ErrInvalidTransition is matched by a StateError
+	ErrInvalidTransition = errors.New("i18n: invalid state transition")
+
+	// ErrNoReviewer is returned when a translation is approved or rejected
+	// without saying who reviewed it
+	ErrNoReviewer = errors.New("i18n: reviewer is required")
+)
+
+// StateError is returned when a translation can not be moved to a state, it
+// matches ErrInvalidTransition
+type StateError struct {
+	Translation *Translation
+	From        State
+	To          State
+}
+
+func (err *StateError) Error() string {
+	return fmt.Sprintf("i18n: %s in %s can not be moved from %s to %s", err.Translation.Key, err.Translation.Lang, err.From, err.To)
+}
+
+// Is reports whether target is ErrInvalidTransition
+func (err *StateError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// state gets the state of a translation, translations without one are
+// approved
+func (translation *Translation) state() State {
+	if translation.State == "" {
+		return StateApproved
+	}
+	return translation.State
+}
+
+// approvedVersion gets the version of a translation served outside preview
+// mode, if there is one
+func (translation *Translation) approvedVersion() *Translation {
+	if translation.state() != StateApproved {
+		return translation.Approved
+	}
+
+	approved := *translation
+	approved.Approved = nil
+	return &approved
+}
+
+// servable checks whether a translation is served, drafts and translations
+// waiting for review are only served in preview mode
+func (translation *Translation) servable(preview bool) bool {
+	switch translation.state() {
+	case StateApproved:
+		return true
+	case StateDraft, StateNeedsReview:
+		return preview
+	}
+	return false
+}
+
+// ParseState parses a review state, an empty state is approved
+func ParseState(s string) (State, error) {
+	switch state := State(s); state {
+	case "":
+		return StateApproved, nil
+	case StateDraft, StateNeedsReview, StateApproved, StateRejected:
+		return state, nil
+	}
+	return "", fmt.Errorf("i18n: unknown state %q", s)
+}
+
+// preview checks whether the context asks for preview mode
+func preview(ctx context.Context) bool {
+	if options := GetOptionsFromContext(ctx); options != nil {
+		return options.Preview
+	}
+	return false
+}
+
+// StateStorage is implemented by storages that can get the translations in
+// some states without reading every translation
+type StateStorage interface {
+	Storage
+
+	GetAllInState(states ...State) ([]*Translation, error)
+}
+
+// GetAllInState gets the translations in a storage that are in any of the
+// states given, storages that do not implement StateStorage have every
+// translation read and filtered
+func GetAllInState(storage Storage, states ...State) ([]*Translation, error) {
+	if s, ok := unwrap(storage).(StateStorage); ok {
+		return s.GetAllInState(states...)
+	}
+
+	all, err := storage.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	return InState(all, states...), nil
+}
+
+// InState filters translations to those in any of the states given,
+// translations without a state are approved
+func InState(translations []*Translation, states ...State) []*Translation {
+	wanted := make(map[State]bool, len(states))
+	for _, state := range states {
+		if state == "" {
+			state = StateApproved
+		}
+		wanted[state] = true
+	}
+
+	var filtered []*Translation
+	for _, translation := range translations {
+		if wanted[translation.state()] {
+			filtered = append(filtered, translation)
+		}
+	}
+
+	return filtered
+}
+
+// Submit moves a draft translation to needs review
+func (i18n *I18n) Submit(lang language.Tag, key string) error {
+	return i18n.SubmitContext(context.Background(), lang, key)
+}
+
+// SubmitContext submits a translation as Submit, storages are not written
+// once the context is done
+func (i18n *I18n) SubmitContext(ctx context.Context, lang language.Tag, key string) error {
+	return i18n.transition(ctx, "Submit", lang, key, StateNeedsReview, "")
+}
+
+// Approve moves a translation waiting for review to approved so it is
+// served in place of any approved version kept alongside it, the reviewer is
+// recorded in its metadata and ErrNoReviewer is returned if it is empty
+func (i18n *I18n) Approve(lang language.Tag, key string, reviewer string) error {
+	return i18n.ApproveContext(context.Background(), lang, key, reviewer)
+}
+
+// ApproveContext approves a translation as Approve, storages are not written
+// once the context is done
+func (i18n *I18n) ApproveContext(ctx context.Context, lang language.Tag, key string, reviewer string) error {
+	if reviewer == "" {
+		return ErrNoReviewer
+	}
+	return i18n.transition(ctx, "Approve", lang, key, StateApproved, reviewer)
+}
+
+// Reject moves a translation waiting for review to rejected, the reviewer is
+// recorded in its metadata and ErrNoReviewer is returned if it is empty
+func (i18n *I18n) Reject(lang language.Tag, key string, reviewer string) error {
+	return i18n.RejectContext(context.Background(), lang, key, reviewer)
+}
+
+// RejectContext rejects a translation as Reject, storages are not written
+// once the context is done
+func (i18n *I18n) RejectContext(ctx context.Context, lang language.Tag, key string, reviewer string) error {
+	if reviewer == "" {
+		return ErrNoReviewer
+	}
+	return i18n.transition(ctx, "Reject", lang, key, StateRejected, reviewer)
+}
+
+// Withdraw moves a translation waiting for review or rejected back to draft
+func (i18n *I18n) Withdraw(lang language.Tag, key string) error {
+	return i18n.WithdrawContext(context.Background(), lang, key)
+}
+
+// WithdrawContext withdraws a translation as Withdraw, storages are not
+// written once the context is done
+func (i18n *I18n) WithdrawContext(ctx context.Context, lang language.Tag, key string) error {
+	return i18n.transition(ctx, "Withdraw", lang, key, StateDraft, "")
+}
+
+// transition moves a translation to a state, the translation must exist in
+// the language itself rather than a fallback
+func (i18n *I18n) transition(ctx context.Context, op string, lang language.Tag, key string, to State, reviewer string) error {
+	i18n.loaded(lang)
+
+	i18n.lock.Lock()
+	defer i18n.lock.Unlock()
+
+	c := i18n.load()
+
+	entry := c.entry(lang, key)
+	if entry == nil {
+		return ErrNotFound
+	}
+
+	from := entry.translation.state()
+	if !allowed(from, to) {
+		return &StateError{
+			Translation: entry.translation,
+			From:        from,
+			To:          to,
+		}
+	}
+
+	translation := *entry.translation
+	translation.State = to
+	translation.Metadata = entry.translation.Metadata.copy()
+
+	// An approved revision replaces the version it was kept alongside
+	if to == StateApproved {
+		translation.Approved = nil
+	}
+
+	if translation.Metadata == nil {
+		translation.Metadata = new(Metadata)
+	}
+
+	now := time.Now()
+	translation.Metadata.Updated = now
+
+	if reviewer != "" {
+		translation.Metadata.ReviewedBy = reviewer
+		translation.Metadata.Reviewed = now
+	}
+
+	err := i18n.write(ctx, storeTranslations(op, []*Translation{&translation}))
+	if err != nil {
+		return err
+	}
+
+	i18n.commit([]*Translation{&translation}, []*Message{entry.message}, nil)
+
+	return nil
+}
+
+func allowed(from State, to State) bool {
+	for _, state := range transitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+package i18n
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/text/language"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReview(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given an approved translation and its draft in another language", t, func() {
+		storage := NewInMemoryStorage()
+		i18n := New(storage)
+		i18n.SetFallback(language.French, language.English)
+
+		So(i18n.AddMany([]*Translation{
+			{Lang: language.English, Key: "hello", Value: "Hello"},
+			{Lang: language.French, Key: "hello", Value: "Bonjour", State: StateDraft},
+		}), ShouldBeNil)
+
+		Convey("Then only the approved translation should be served", func() {
+			So(i18n.T(language.French, "hello"), ShouldEqual, "Hello")
+			So(i18n.Lookup(language.French, "hello").Resolved, ShouldResemble, language.English)
+		})
+
+		Convey("Then the draft should be served in preview mode", func() {
+			ctx := NewPreviewContext(NewLanguageContext(context.Background(), language.French))
+
+			So(i18n.TCtx(ctx, "hello"), ShouldEqual, "Bonjour")
+			So(i18n.GetContext(ctx, language.French, "hello").Value, ShouldEqual, "Bonjour")
+		})
+
+		Convey("When the draft is submitted and approved", func() {
+			So(i18n.Submit(language.French, "hello"), ShouldBeNil)
+			So(i18n.T(language.French, "hello"), ShouldEqual, "Hello")
+
+			So(i18n.Approve(language.French, "hello", "alice"), ShouldBeNil)
+
+			Convey("Then it should be served", func() {
+				So(i18n.T(language.French, "hello"), ShouldEqual, "Bonjour")
+			})
+
+			Convey("Then the reviewer should be recorded in storage", func() {
+				results, err := GetAllInState(storage, StateApproved)
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 2)
+
+				translation := i18n.Get(language.French, "hello")
+				So(translation.State, ShouldEqual, StateApproved)
+				So(translation.Metadata.ReviewedBy, ShouldEqual, "alice")
+				So(translation.Metadata.Reviewed.IsZero(), ShouldBeFalse)
+			})
+		})
+
+		Convey("When the draft is submitted and rejected", func() {
+			So(i18n.Submit(language.French, "hello"), ShouldBeNil)
+			So(i18n.Reject(language.French, "hello", "alice"), ShouldBeNil)
+
+			Convey("Then it should not be served even in preview mode", func() {
+				ctx := NewPreviewContext(context.Background())
+				So(i18n.GetContext(ctx, language.French, "hello").Value, ShouldEqual, "Hello")
+			})
+
+			Convey("Then it should be filterable by state", func() {
+				results, err := GetAllInState(storage, StateRejected)
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Metadata.ReviewedBy, ShouldEqual, "alice")
+			})
+
+			Convey("Then it can be moved back to draft", func() {
+				So(i18n.Withdraw(language.French, "hello"), ShouldBeNil)
+				So(i18n.GetContext(NewPreviewContext(context.Background()), language.French, "hello").State, ShouldEqual, StateDraft)
+			})
+		})
+
+		Convey("When the draft is submitted", func() {
+			before := i18n.Get(language.French, "hello")
+			So(i18n.Submit(language.French, "hello"), ShouldBeNil)
+
+			Convey("Then its updated time should be set", func() {
+				translation := i18n.GetContext(NewPreviewContext(context.Background()), language.French, "hello")
+				So(translation.Metadata.Updated.IsZero(), ShouldBeFalse)
+				So(translation.Metadata.Updated.Before(time.Now().Add(time.Second)), ShouldBeTrue)
+				So(i18n.Get(language.French, "hello"), ShouldResemble, before)
+			})
+
+			Convey("Then it can not be approved without a reviewer", func() {
+				So(i18n.Approve(language.French, "hello", ""), ShouldEqual, ErrNoReviewer)
+				So(i18n.Reject(language.French, "hello", ""), ShouldEqual, ErrNoReviewer)
+				So(i18n.GetContext(NewPreviewContext(context.Background()), language.French, "hello").State, ShouldEqual, StateNeedsReview)
+			})
+
+			Convey("Then it should not be approved once the context is done", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				So(errors.Is(i18n.ApproveContext(ctx, language.French, "hello", "alice"), context.Canceled), ShouldBeTrue)
+				So(i18n.T(language.French, "hello"), ShouldEqual, "Hello")
+			})
+		})
+
+		Convey("When a draft is approved without review", func() {
+			err := i18n.Approve(language.French, "hello", "alice")
+
+			Convey("Then a state error should be returned", func() {
+				So(errors.Is(err, ErrInvalidTransition), ShouldBeTrue)
+
+				var stateErr *StateError
+				So(errors.As(err, &stateErr), ShouldBeTrue)
+				So(stateErr.From, ShouldEqual, StateDraft)
+				So(stateErr.To, ShouldEqual, StateApproved)
+			})
+		})
+
+		Convey("Then the draft should be looked up in preview mode", func() {
+			ctx := NewPreviewContext(context.Background())
+
+			So(i18n.LookupContext(ctx, language.French, "hello").Value, ShouldEqual, "Bonjour")
+			So(i18n.Lookup(language.French, "hello").Value, ShouldEqual, "Hello")
+		})
+
+		Convey("Then the draft should not be listed or searched", func() {
+			So(i18n.Keys(""), ShouldResemble, []string{"hello"})
+			So(i18n.Languages(), ShouldResemble, []language.Tag{language.English})
+			So(i18n.Search("bonjour"), ShouldBeEmpty)
+		})
+
+		Convey("When a missing translation is submitted", func() {
+			err := i18n.Submit(language.German, "hello")
+
+			Convey("Then a not found error should be returned", func() {
+				So(err, ShouldEqual, ErrNotFound)
+			})
+		})
+	})
+}
+
+func TestReviewRevision(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given an approved translation", t, func() {
+		storage := NewInMemoryStorage()
+		i18n := New(storage)
+
+		So(i18n.Add(&Translation{Lang: language.English, Key: "menu.hello", Value: "Hello"}), ShouldBeNil)
+
+		Convey("When a draft revision is added", func() {
+			So(i18n.Add(&Translation{Lang: language.English, Key: "menu.hello", Value: "Hi", State: StateDraft}), ShouldBeNil)
+
+			Convey("Then the approved version should still be served", func() {
+				So(i18n.T(language.English, "menu.hello"), ShouldEqual, "Hello")
+				So(i18n.Search("hello"), ShouldHaveLength, 1)
+			})
+
+			Convey("Then the revision should be served in preview mode", func() {
+				ctx := NewPreviewContext(context.Background())
+				So(i18n.LookupContext(ctx, language.English, "menu.hello").Value, ShouldEqual, "Hi")
+			})
+
+			Convey("Then the approved version should be kept in storage", func() {
+				results, err := GetAllInState(storage, StateDraft)
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Approved, ShouldNotBeNil)
+				So(results[0].Approved.Value, ShouldEqual, "Hello")
+			})
+
+			Convey("Then the approved version should be kept when the revision is rejected", func() {
+				So(i18n.Submit(language.English, "menu.hello"), ShouldBeNil)
+				So(i18n.T(language.English, "menu.hello"), ShouldEqual, "Hello")
+
+				So(i18n.Reject(language.English, "menu.hello", "alice"), ShouldBeNil)
+				So(i18n.T(language.English, "menu.hello"), ShouldEqual, "Hello")
+			})
+
+			Convey("Then the revision should replace it once approved", func() {
+				So(i18n.Submit(language.English, "menu.hello"), ShouldBeNil)
+				So(i18n.Approve(language.English, "menu.hello", "alice"), ShouldBeNil)
+
+				So(i18n.T(language.English, "menu.hello"), ShouldEqual, "Hi")
+				So(i18n.Get(language.English, "menu.hello").Approved, ShouldBeNil)
+			})
+
+			Convey("Then the revision and approved version should be moved together", func() {
+				So(i18n.Group("menu").MoveTo("nav"), ShouldBeNil)
+
+				So(i18n.T(language.English, "nav.hello"), ShouldEqual, "Hello")
+
+				ctx := NewPreviewContext(context.Background())
+				So(i18n.LookupContext(ctx, language.English, "nav.hello").Value, ShouldEqual, "Hi")
+			})
+		})
+	})
+
+	Convey("Given a draft without an approved version", t, func() {
+		i18n := New(NewInMemoryStorage())
+
+		So(i18n.Add(&Translation{Lang: language.English, Key: "menu.new", Value: "New", State: StateDraft}), ShouldBeNil)
+
+		Convey("When its group is moved", func() {
+			So(i18n.Group("menu").MoveTo("nav"), ShouldBeNil)
+
+			Convey("Then the draft should be moved", func() {
+				ctx := NewPreviewContext(context.Background())
+				So(i18n.LookupContext(ctx, language.English, "nav.new").Value, ShouldEqual, "New")
+				So(i18n.LookupContext(ctx, language.English, "menu.new").Value, ShouldBeEmpty)
+			})
+		})
+	})
+}
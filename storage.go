@@ -3,6 +3,7 @@ package i18n
 import (
 	"sync"
 
+	"golang.org/x/net/context"
 	"golang.org/x/text/language"
 )
 
@@ -20,12 +21,140 @@ type Storage interface {
 	DeleteSupportedLanguage(language.Tag) error
 }
 
+// ContextStorage is a Storage whose operations accept a context, so slow or
+// hung backends honor deadlines and cancellation
+type ContextStorage interface {
+	Storage
+
+	GetAllContext(context.Context) ([]*Translation, error)
+	StoreContext(context.Context, *Translation) error
+	DeleteContext(context.Context, *Translation) error
+
+	DefaultLanguageContext(context.Context) (language.Tag, error)
+	SupportedLanguagesContext(context.Context) ([]language.Tag, error)
+
+	SetDefaultLanguageContext(context.Context, language.Tag) error
+	StoreSupportedLanguageContext(context.Context, language.Tag) error
+	DeleteSupportedLanguageContext(context.Context, language.Tag) error
+}
+
+// WithContext returns the storage as a ContextStorage. Storages that do not
+// implement it are adapted, reads return early if the context is done before
+// they complete and writes are not started once it is done.
+func WithContext(storage Storage) ContextStorage {
+	if s, ok := storage.(ContextStorage); ok {
+		return s
+	}
+	return &contextStorage{storage}
+}
+
+type contextStorage struct {
+	Storage
+}
+
+// Await runs a read, returning early if the context is done first. The read
+// can not be stopped so one that is abandoned keeps running in the background
+// until it returns, storages should bound their reads with timeouts of their
+// own.
+func Await(ctx context.Context, f func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- f()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// unwrap gets the storage adapted by WithContext, so optional interfaces such
+// as BatchStorage are checked on the storage itself rather than the adapter
+func unwrap(storage Storage) Storage {
+	if s, ok := storage.(*contextStorage); ok {
+		return s.Storage
+	}
+	return storage
+}
+
+func (storage *contextStorage) GetAllContext(ctx context.Context) ([]*Translation, error) {
+	var translations []*Translation
+	err := Await(ctx, func() (err error) {
+		translations, err = storage.GetAll()
+		return err
+	})
+	return translations, err
+}
+
+func (storage *contextStorage) StoreContext(ctx context.Context, translation *Translation) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return storage.Store(translation)
+}
+
+func (storage *contextStorage) DeleteContext(ctx context.Context, translation *Translation) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return storage.Delete(translation)
+}
+
+func (storage *contextStorage) DefaultLanguageContext(ctx context.Context) (language.Tag, error) {
+	tag := language.Und
+	err := Await(ctx, func() (err error) {
+		tag, err = storage.DefaultLanguage()
+		return err
+	})
+	return tag, err
+}
+
+func (storage *contextStorage) SupportedLanguagesContext(ctx context.Context) ([]language.Tag, error) {
+	var tags []language.Tag
+	err := Await(ctx, func() (err error) {
+		tags, err = storage.SupportedLanguages()
+		return err
+	})
+	return tags, err
+}
+
+func (storage *contextStorage) SetDefaultLanguageContext(ctx context.Context, tag language.Tag) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return storage.SetDefaultLanguage(tag)
+}
+
+func (storage *contextStorage) StoreSupportedLanguageContext(ctx context.Context, tag language.Tag) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return storage.StoreSupportedLanguage(tag)
+}
+
+func (storage *contextStorage) DeleteSupportedLanguageContext(ctx context.Context, tag language.Tag) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return storage.DeleteSupportedLanguage(tag)
+}
+
 type inMemoryStorage struct {
 	lock         sync.RWMutex
 	translations []*Translation
 
-	defaultLang    language.Tag
-	supportedLangs []language.Tag
+	defaultLang       language.Tag
+	supportedLangs    []language.Tag
+	fallbacks         []*Fallback
+	fallbackToDefault bool
+
+	feed changeFeed
 }
 
 // NewInMemoryStorage Creates a non persistent in memory translation store
@@ -58,6 +187,7 @@ func (storage *inMemoryStorage) StoreSupportedLanguage(tag language.Tag) error {
 	}
 
 	storage.supportedLangs = append(storage.supportedLangs, tag)
+	storage.feed.publish(&Change{Type: ChangeLanguages})
 
 	return nil
 }
@@ -69,6 +199,7 @@ func (storage *inMemoryStorage) DeleteSupportedLanguage(tag language.Tag) error
 	for i, l := range storage.supportedLangs {
 		if l.String() == tag.String() {
 			storage.supportedLangs = append(storage.supportedLangs[:i], storage.supportedLangs[i+1:]...)
+			storage.feed.publish(&Change{Type: ChangeLanguages})
 			return nil
 		}
 	}
@@ -86,6 +217,66 @@ func (storage *inMemoryStorage) SetDefaultLanguage(tag language.Tag) error {
 	defer storage.lock.Unlock()
 
 	storage.defaultLang = tag
+	storage.feed.publish(&Change{Type: ChangeLanguages})
+
+	return nil
+}
+
+func (storage *inMemoryStorage) Fallbacks() ([]*Fallback, error) {
+	storage.lock.RLock()
+	defer storage.lock.RUnlock()
+
+	return storage.fallbacks, nil
+}
+
+func (storage *inMemoryStorage) StoreFallback(fallback *Fallback) error {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
+	fallback = fallback.copy()
+
+	for i, f := range storage.fallbacks {
+		if f.Lang.String() == fallback.Lang.String() {
+			storage.fallbacks[i] = fallback
+			storage.feed.publish(&Change{Type: ChangeLanguages})
+			return nil
+		}
+	}
+
+	storage.fallbacks = append(storage.fallbacks, fallback)
+	storage.feed.publish(&Change{Type: ChangeLanguages})
+
+	return nil
+}
+
+func (storage *inMemoryStorage) DeleteFallback(tag language.Tag) error {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
+	for i, f := range storage.fallbacks {
+		if f.Lang.String() == tag.String() {
+			storage.fallbacks = append(storage.fallbacks[:i:i], storage.fallbacks[i+1:]...)
+			storage.feed.publish(&Change{Type: ChangeLanguages})
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (storage *inMemoryStorage) FallbackToDefault() (bool, error) {
+	storage.lock.RLock()
+	defer storage.lock.RUnlock()
+
+	return storage.fallbackToDefault, nil
+}
+
+func (storage *inMemoryStorage) SetFallbackToDefault(enabled bool) error {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
+	storage.fallbackToDefault = enabled
+	storage.feed.publish(&Change{Type: ChangeLanguages})
 
 	return nil
 }
@@ -97,20 +288,60 @@ func (storage *inMemoryStorage) GetAll() ([]*Translation, error) {
 	return storage.translations, nil
 }
 
+func (storage *inMemoryStorage) GetLanguage(tag language.Tag) ([]*Translation, error) {
+	storage.lock.RLock()
+	defer storage.lock.RUnlock()
+
+	lang := tag.String()
+
+	var translations []*Translation
+	for _, t := range storage.translations {
+		if t.Lang.String() == lang {
+			translations = append(translations, t)
+		}
+	}
+
+	return translations, nil
+}
+
+func (storage *inMemoryStorage) GetKey(tag language.Tag, key string) (*Translation, error) {
+	storage.lock.RLock()
+	defer storage.lock.RUnlock()
+
+	lang := tag.String()
+
+	for _, t := range storage.translations {
+		if t.Lang.String() == lang && t.Key == key {
+			return t, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (storage *inMemoryStorage) GetAllInState(states ...State) ([]*Translation, error) {
+	storage.lock.RLock()
+	defer storage.lock.RUnlock()
+
+	return InState(storage.translations, states...), nil
+}
+
 func (storage *inMemoryStorage) Store(translation *Translation) error {
 	storage.lock.Lock()
 	defer storage.lock.Unlock()
 
-	for _, t := range storage.translations {
+	for i, t := range storage.translations {
 		sameLang := t.Lang.String() == translation.Lang.String()
 		sameKey := t.Key == translation.Key
 		if sameLang && sameKey {
-			t.Value = translation.Value
+			storage.translations[i] = translation
+			storage.feed.publish(&Change{Type: ChangeUpsert, Translation: translation})
 			return nil
 		}
 	}
 
 	storage.translations = append(storage.translations, translation)
+	storage.feed.publish(&Change{Type: ChangeUpsert, Translation: translation})
 
 	return nil
 }
@@ -124,9 +355,103 @@ func (storage *inMemoryStorage) Delete(translation *Translation) error {
 		sameKey := t.Key == translation.Key
 		if sameLang && sameKey {
 			storage.translations, storage.translations[len(storage.translations)-1] = append(storage.translations[:i], storage.translations[i+1:]...), nil
+			storage.feed.publish(&Change{Type: ChangeDelete, Translation: translation})
 			return nil
 		}
 	}
 
 	return nil
 }
+
+func (storage *inMemoryStorage) StoreMany(translations []*Translation) error {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
+	storage.storeMany(translations)
+	storage.feed.publish(ChangesFor(ChangeUpsert, translations)...)
+
+	return nil
+}
+
+// storeMany adds or updates translations, the lock must be held
+func (storage *inMemoryStorage) storeMany(translations []*Translation) {
+	index := make(map[string]int, len(storage.translations))
+	for i, t := range storage.translations {
+		index[batchKey(t)] = i
+	}
+
+	for _, translation := range translations {
+		if i, ok := index[batchKey(translation)]; ok {
+			storage.translations[i] = translation
+			continue
+		}
+
+		index[batchKey(translation)] = len(storage.translations)
+		storage.translations = append(storage.translations, translation)
+	}
+}
+
+func (storage *inMemoryStorage) DeleteMany(translations []*Translation) error {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
+	storage.deleteMany(translations)
+	storage.feed.publish(ChangesFor(ChangeDelete, translations)...)
+
+	return nil
+}
+
+// deleteMany removes translations, the lock must be held
+func (storage *inMemoryStorage) deleteMany(translations []*Translation) {
+	remove := make(map[string]bool, len(translations))
+	for _, translation := range translations {
+		remove[batchKey(translation)] = true
+	}
+
+	kept := make([]*Translation, 0, len(storage.translations))
+	for _, t := range storage.translations {
+		if !remove[batchKey(t)] {
+			kept = append(kept, t)
+		}
+	}
+	storage.translations = kept
+}
+
+func (storage *inMemoryStorage) Move(store []*Translation, remove []*Translation) error {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
+	stored := make(map[string]bool, len(store))
+	for _, translation := range store {
+		stored[batchKey(translation)] = true
+	}
+
+	var deleted []*Translation
+	for _, translation := range remove {
+		if !stored[batchKey(translation)] {
+			deleted = append(deleted, translation)
+		}
+	}
+
+	storage.deleteMany(deleted)
+	storage.storeMany(store)
+	storage.feed.publish(ChangesFor(ChangeUpsert, store)...)
+	storage.feed.publish(ChangesFor(ChangeDelete, deleted)...)
+
+	return nil
+}
+
+func (storage *inMemoryStorage) ReplaceAll(translations []*Translation) error {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
+	storage.translations = nil
+	storage.storeMany(translations)
+	storage.feed.publish(&Change{Type: ChangeReload})
+
+	return nil
+}
+
+func (storage *inMemoryStorage) Watch(stop <-chan struct{}) (<-chan *Change, error) {
+	return storage.feed.watch(stop), nil
+}
@@ -2,32 +2,79 @@ package redis
 
 import (
 	"encoding/json"
+	"strings"
 
 	"github.com/ThatsMrTalbot/i18n"
+	"github.com/ThatsMrTalbot/i18n/internal/codec"
 	"golang.org/x/text/language"
 )
 
-type translationObject struct {
-	Lang  string `json:"lang"`
-	Key   string `json:"key"`
-	Value string `json:"value"`
-}
-
 func encode(t *i18n.Translation) string {
-	data, _ := json.Marshal(&translationObject{
-		Lang:  t.Lang.String(),
-		Key:   t.Key,
-		Value: t.Value,
-	})
+	data, _ := json.Marshal(codec.EncodeTranslation(t))
 	return string(data)
 }
 
 func decode(t string) (*i18n.Translation, error) {
-	var obj translationObject
+	var obj codec.Translation
 	err := json.Unmarshal([]byte(t), &obj)
-	return &i18n.Translation{
-		Lang:  language.Make(obj.Lang),
-		Key:   obj.Key,
-		Value: obj.Value,
-	}, err
+	return codec.DecodeTranslation(&obj), err
+}
+
+func encodeChange(c *i18n.Change) string {
+	data, _ := json.Marshal(codec.EncodeChange(c))
+	return string(data)
+}
+
+func decodeChange(c string) (*i18n.Change, error) {
+	var obj codec.Change
+	if err := json.Unmarshal([]byte(c), &obj); err != nil {
+		return nil, err
+	}
+
+	return codec.DecodeChange(&obj), nil
+}
+
+func encodeFallback(f *i18n.Fallback) (string, string) {
+	chain := make([]string, 0, len(f.Chain))
+	for _, tag := range f.Chain {
+		chain = append(chain, tag.String())
+	}
+	return f.Lang.String(), strings.Join(chain, ",")
+}
+
+func decodeFallback(lang string, chain string) *i18n.Fallback {
+	f := &i18n.Fallback{
+		Lang: language.Make(lang),
+	}
+
+	if chain == "" {
+		return f
+	}
+
+	for _, tag := range strings.Split(chain, ",") {
+		f.Chain = append(f.Chain, language.Make(tag))
+	}
+	return f
+}
+
+// key identifies a translation by language and key
+func key(t *i18n.Translation) string {
+	return t.Lang.String() + "\x00" + t.Key
+}
+
+// encodeMany encodes translations along with the set of keys they cover, later
+// translations win when the same key is given more than once
+func encodeMany(translations []*i18n.Translation) ([]string, map[string]bool) {
+	values := make([]string, 0, len(translations))
+	keys := make(map[string]bool, len(translations))
+
+	for i := len(translations) - 1; i >= 0; i-- {
+		k := key(translations[i])
+		if !keys[k] {
+			keys[k] = true
+			values = append(values, encode(translations[i]))
+		}
+	}
+
+	return values, keys
 }
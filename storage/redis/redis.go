@@ -1,6 +1,10 @@
 package redis
 
 import (
+	"time"
+
+	"golang.org/x/net/context"
+
 	"github.com/ThatsMrTalbot/i18n"
 	"golang.org/x/text/language"
 	"gopkg.in/redis.v3"
@@ -10,40 +14,267 @@ const (
 	RedisKey                   = "i18n_translations"
 	RedisDefaultLanguageKey    = "i18n_translations_default"
 	RedisSupportedLanguagesKey = "i18n_translations_supported"
+	RedisFallbackKey           = "i18n_translations_fallback"
+	RedisFallbackToDefaultKey  = "i18n_translations_fallback_default"
+	RedisChangeChannel         = "i18n_translations_changes"
+
+	// RedisLanguageKeyPrefix prefixes a hash per language of the stored
+	// translations by key, read by GetLanguage. RedisStateKeyPrefix prefixes
+	// a hash per review state, read by GetAllInState. RedisIndexedKey is set
+	// once the hashes have been built from RedisKey.
+	RedisLanguageKeyPrefix = "i18n_translations_lang:"
+	RedisStateKeyPrefix    = "i18n_translations_state:"
+	RedisIndexedKey        = "i18n_translations_indexed"
 )
 
+// languageKey is the hash holding the translations of a language
+func languageKey(tag language.Tag) string {
+	return RedisLanguageKeyPrefix + tag.String()
+}
+
+// stateKey is the hash holding the translations in a review state,
+// translations without a state are approved
+func stateKey(state i18n.State) string {
+	if state == "" {
+		state = i18n.StateApproved
+	}
+	return RedisStateKeyPrefix + string(state)
+}
+
+// indexed checks if the language hashes have been built
+func indexed(cmd interface {
+	Get(string) *redis.StringCmd
+}) (bool, error) {
+	err := cmd.Get(RedisIndexedKey).Err()
+	if err == redis.Nil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
 type Storage struct {
-	client *redis.Client
+	client   *redis.Client
+	observer i18n.Observer
+	logger   i18n.Logger
+}
+
+// wrap records the operation and backend on redis errors, a missing value is
+// reported as i18n.ErrNotFound
+func wrap(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if err == redis.Nil {
+		err = i18n.ErrNotFound
+	}
+
+	return &i18n.StorageError{
+		Backend: "redis",
+		Op:      op,
+		Err:     err,
+	}
+}
+
+// maxRetries bounds how many times a transaction aborted by concurrent writes
+// is run again
+const maxRetries = 10
+
+// retry runs a transaction again, after a short backoff, while it is aborted
+// by a change to a watched key. It stops once the context is done or after
+// maxRetries, returning the last error.
+func (storage *Storage) retry(ctx context.Context, op string, f func() error) error {
+	for retries := 0; ; retries++ {
+		err := f()
+		if err != redis.TxFailedErr {
+			if retries > 0 {
+				storage.log().Info("transaction succeeded after retries", "op", op, "retries", retries)
+			}
+			return err
+		}
+
+		if retries == maxRetries {
+			storage.log().Warn("transaction aborted by concurrent writes, giving up", "op", op, "retries", retries)
+			return err
+		}
+
+		storage.log().Debug("transaction aborted by a concurrent write, retrying", "op", op, "retries", retries+1)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(i18n.Backoff(retries+1, time.Millisecond, 100*time.Millisecond)):
+		}
+	}
 }
 
+// size is the number of bytes in a set of values
+func size(values []string) int {
+	n := 0
+	for _, value := range values {
+		n += len(value)
+	}
+	return n
+}
+
+// Watch retries receiving changes after a failure with a backoff between
+// these bounds
+const (
+	watchMinBackoff = time.Second
+	watchMaxBackoff = time.Minute
+)
+
+// timeout bounds every command sent by a storage made with Connect. Redis
+// commands can not be cancelled, a read abandoned because its context is done
+// finishes in the background within the client timeouts.
+const timeout = 5 * time.Second
+
+// New creates a storage using the client. Reads honor context deadlines but
+// the command they abandon runs until the client times it out, so the client
+// should have a ReadTimeout and WriteTimeout set.
 func New(client *redis.Client) *Storage {
 	return &Storage{
 		client: client,
 	}
 }
 
+// Connect creates a storage with a new client, commands time out after five
+// seconds
 func Connect(addr string, password string, db int64) (*Storage, error) {
 	client := redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: password,
-		DB:       db,
+		Addr:         addr,
+		Password:     password,
+		DB:           db,
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
 	})
 
 	_, err := client.Ping().Result()
 
 	if err != nil {
-		return nil, err
+		return nil, wrap("Connect", err)
 	}
 
 	return New(client), nil
 }
 
+// SetObserver sets the observer notified of every operation made against
+// redis, it must be set before the storage is used
+func (storage *Storage) SetObserver(observer i18n.Observer) {
+	storage.observer = observer
+}
+
+// SetLogger sets the logger for transaction retries and decode failures, it
+// must be set before the storage is used
+func (storage *Storage) SetLogger(logger i18n.Logger) {
+	storage.logger = logger
+}
+
+func (storage *Storage) log() i18n.Logger {
+	if storage.logger != nil {
+		return storage.logger
+	}
+	return i18n.NopLogger
+}
+
+// observe reports an operation to the observer if one is set
+func (storage *Storage) observe(ctx context.Context, op string, start time.Time, bytes int, err error) {
+	if storage.observer == nil {
+		return
+	}
+
+	storage.observer.ObserveStorage(ctx, &i18n.StorageEvent{
+		Backend:  "redis",
+		Op:       op,
+		Start:    start,
+		Duration: time.Since(start),
+		Bytes:    bytes,
+		Err:      err,
+	})
+}
+
 func (storage *Storage) SupportedLanguages() ([]language.Tag, error) {
-	cmd := storage.client.LRange(RedisSupportedLanguagesKey, 0, -1)
-	results, err := cmd.Result()
+	return storage.SupportedLanguagesContext(context.Background())
+}
+
+func (storage *Storage) DefaultLanguage() (language.Tag, error) {
+	return storage.DefaultLanguageContext(context.Background())
+}
+
+func (storage *Storage) StoreSupportedLanguage(tag language.Tag) error {
+	return storage.StoreSupportedLanguageContext(context.Background(), tag)
+}
+
+func (storage *Storage) DeleteSupportedLanguage(tag language.Tag) error {
+	return storage.DeleteSupportedLanguageContext(context.Background(), tag)
+}
+
+func (storage *Storage) SetDefaultLanguage(tag language.Tag) error {
+	return storage.SetDefaultLanguageContext(context.Background(), tag)
+}
+
+func (storage *Storage) Fallbacks() ([]*i18n.Fallback, error) {
+	return storage.FallbacksContext(context.Background())
+}
+
+func (storage *Storage) StoreFallback(fallback *i18n.Fallback) error {
+	return storage.StoreFallbackContext(context.Background(), fallback)
+}
+
+func (storage *Storage) DeleteFallback(tag language.Tag) error {
+	return storage.DeleteFallbackContext(context.Background(), tag)
+}
+
+func (storage *Storage) FallbackToDefault() (bool, error) {
+	return storage.FallbackToDefaultContext(context.Background())
+}
+
+func (storage *Storage) SetFallbackToDefault(enabled bool) error {
+	return storage.SetFallbackToDefaultContext(context.Background(), enabled)
+}
+
+func (storage *Storage) GetAll() ([]*i18n.Translation, error) {
+	return storage.GetAllContext(context.Background())
+}
+
+// GetAllInState gets the translations in any of the states given without
+// reading the rest
+func (storage *Storage) GetAllInState(states ...i18n.State) ([]*i18n.Translation, error) {
+	return storage.GetAllInStateContext(context.Background(), states...)
+}
+
+// GetLanguage gets the translations of a single language without reading
+// the rest
+func (storage *Storage) GetLanguage(tag language.Tag) ([]*i18n.Translation, error) {
+	return storage.GetLanguageContext(context.Background(), tag)
+}
+
+// GetKey gets a single translation without reading the rest of its language
+func (storage *Storage) GetKey(tag language.Tag, key string) (*i18n.Translation, error) {
+	return storage.GetKeyContext(context.Background(), tag, key)
+}
+
+func (storage *Storage) Store(t *i18n.Translation) error {
+	return storage.StoreContext(context.Background(), t)
+}
+
+func (storage *Storage) Delete(t *i18n.Translation) error {
+	return storage.DeleteContext(context.Background(), t)
+}
+
+func (storage *Storage) SupportedLanguagesContext(ctx context.Context) ([]language.Tag, error) {
+	start := time.Now()
+
+	var results []string
+	err := i18n.Await(ctx, func() (err error) {
+		results, err = storage.client.LRange(RedisSupportedLanguagesKey, 0, -1).Result()
+		return err
+	})
 	if err != nil {
-		return nil, err
+		storage.observe(ctx, "SupportedLanguages", start, 0, err)
+		return nil, wrap("SupportedLanguages", err)
 	}
+	storage.observe(ctx, "SupportedLanguages", start, size(results), nil)
 
 	langs := make([]language.Tag, 0, len(results))
 
@@ -54,164 +285,760 @@ func (storage *Storage) SupportedLanguages() ([]language.Tag, error) {
 	return langs, nil
 }
 
-func (storage *Storage) DefaultLanguage() (language.Tag, error) {
-	cmd := storage.client.Get(RedisDefaultLanguageKey)
-	lang, err := cmd.Result()
-	return language.Make(lang), err
-}
+func (storage *Storage) DefaultLanguageContext(ctx context.Context) (language.Tag, error) {
+	start := time.Now()
 
-func (storage *Storage) StoreSupportedLanguage(tag language.Tag) error {
-	tx, err := storage.client.Watch(RedisSupportedLanguagesKey)
-	if err != nil {
+	var lang string
+	err := i18n.Await(ctx, func() (err error) {
+		lang, err = storage.client.Get(RedisDefaultLanguageKey).Result()
 		return err
+	})
+	if err != nil {
+		storage.observe(ctx, "DefaultLanguage", start, 0, err)
+		return language.Und, wrap("DefaultLanguage", err)
 	}
-	defer tx.Close()
+	storage.observe(ctx, "DefaultLanguage", start, len(lang), nil)
 
-	cmd := tx.LRange(RedisSupportedLanguagesKey, 0, -1)
-	results, err := cmd.Result()
-	if err != nil {
-		return err
+	return language.Make(lang), nil
+}
+
+func (storage *Storage) StoreSupportedLanguageContext(ctx context.Context, tag language.Tag) (err error) {
+	start := time.Now()
+	defer func() { storage.observe(ctx, "StoreSupportedLanguage", start, len(tag.String()), err) }()
+
+	if err := ctx.Err(); err != nil {
+		return wrap("StoreSupportedLanguage", err)
 	}
 
-	_, err = tx.Exec(func() error {
-		for _, result := range results {
-			if result == tag.String() {
-				return nil
+	err = storage.retry(ctx, "StoreSupportedLanguage", func() error {
+		tx, err := storage.client.Watch(RedisSupportedLanguagesKey)
+		if err != nil {
+			return err
+		}
+		defer tx.Close()
+
+		results, err := tx.LRange(RedisSupportedLanguagesKey, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(func() error {
+			for _, result := range results {
+				if result == tag.String() {
+					return nil
+				}
 			}
+			tx.LPush(RedisSupportedLanguagesKey, tag.String())
+			return nil
+		})
+		return err
+	})
+
+	if err == nil {
+		storage.publish(&i18n.Change{Type: i18n.ChangeLanguages})
+	}
+
+	return wrap("StoreSupportedLanguage", err)
+}
+
+func (storage *Storage) DeleteSupportedLanguageContext(ctx context.Context, tag language.Tag) (err error) {
+	start := time.Now()
+	defer func() { storage.observe(ctx, "DeleteSupportedLanguage", start, len(tag.String()), err) }()
+
+	if err := ctx.Err(); err != nil {
+		return wrap("DeleteSupportedLanguage", err)
+	}
+
+	err = storage.retry(ctx, "DeleteSupportedLanguage", func() error {
+		tx, err := storage.client.Watch(RedisSupportedLanguagesKey)
+		if err != nil {
+			return err
 		}
-		tx.LPush(RedisSupportedLanguagesKey, tag.String())
-		return nil
+		defer tx.Close()
+
+		results, err := tx.LRange(RedisSupportedLanguagesKey, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(func() error {
+			for i, result := range results {
+				if result == tag.String() {
+					tx.LSet(RedisSupportedLanguagesKey, int64(i), "~REMOVE~")
+				}
+			}
+			tx.LRem(RedisSupportedLanguagesKey, 0, "~REMOVE~")
+			return nil
+		})
+		return err
 	})
 
-	if err == redis.TxFailedErr {
-		return storage.StoreSupportedLanguage(tag)
+	if err == nil {
+		storage.publish(&i18n.Change{Type: i18n.ChangeLanguages})
 	}
 
-	return err
+	return wrap("DeleteSupportedLanguage", err)
 }
 
-func (storage *Storage) DeleteSupportedLanguage(tag language.Tag) error {
-	tx, err := storage.client.Watch(RedisSupportedLanguagesKey)
-	if err != nil {
+func (storage *Storage) SetDefaultLanguageContext(ctx context.Context, tag language.Tag) (err error) {
+	start := time.Now()
+	defer func() { storage.observe(ctx, "SetDefaultLanguage", start, len(tag.String()), err) }()
+
+	if err := ctx.Err(); err != nil {
+		return wrap("SetDefaultLanguage", err)
+	}
+
+	err = storage.client.Set(RedisDefaultLanguageKey, tag.String(), 0).Err()
+	if err == nil {
+		storage.publish(&i18n.Change{Type: i18n.ChangeLanguages})
+	}
+
+	return wrap("SetDefaultLanguage", err)
+}
+
+func (storage *Storage) FallbacksContext(ctx context.Context) ([]*i18n.Fallback, error) {
+	start := time.Now()
+
+	var results map[string]string
+	err := i18n.Await(ctx, func() (err error) {
+		results, err = storage.client.HGetAllMap(RedisFallbackKey).Result()
 		return err
+	})
+	if err != nil {
+		storage.observe(ctx, "Fallbacks", start, 0, err)
+		return nil, wrap("Fallbacks", err)
+	}
+
+	bytes := 0
+	fallbacks := make([]*i18n.Fallback, 0, len(results))
+
+	for lang, chain := range results {
+		bytes += len(lang) + len(chain)
+		fallbacks = append(fallbacks, decodeFallback(lang, chain))
 	}
-	defer tx.Close()
+	storage.observe(ctx, "Fallbacks", start, bytes, nil)
 
-	cmd := tx.LRange(RedisSupportedLanguagesKey, 0, -1)
-	results, err := cmd.Result()
+	return fallbacks, nil
+}
+
+func (storage *Storage) StoreFallbackContext(ctx context.Context, fallback *i18n.Fallback) (err error) {
+	start := time.Now()
+	lang, chain := encodeFallback(fallback)
+	defer func() { storage.observe(ctx, "StoreFallback", start, len(lang)+len(chain), err) }()
+
+	if err := ctx.Err(); err != nil {
+		return wrap("StoreFallback", err)
+	}
+
+	err = storage.client.HSet(RedisFallbackKey, lang, chain).Err()
+	if err == nil {
+		storage.publish(&i18n.Change{Type: i18n.ChangeLanguages})
+	}
+
+	return wrap("StoreFallback", err)
+}
+
+func (storage *Storage) DeleteFallbackContext(ctx context.Context, tag language.Tag) (err error) {
+	start := time.Now()
+	defer func() { storage.observe(ctx, "DeleteFallback", start, len(tag.String()), err) }()
+
+	if err := ctx.Err(); err != nil {
+		return wrap("DeleteFallback", err)
+	}
+
+	err = storage.client.HDel(RedisFallbackKey, tag.String()).Err()
+	if err == nil {
+		storage.publish(&i18n.Change{Type: i18n.ChangeLanguages})
+	}
+
+	return wrap("DeleteFallback", err)
+}
+
+// FallbackToDefaultContext reports false if the setting was never stored
+func (storage *Storage) FallbackToDefaultContext(ctx context.Context) (bool, error) {
+	start := time.Now()
+
+	var result string
+	err := i18n.Await(ctx, func() (err error) {
+		result, err = storage.client.Get(RedisFallbackToDefaultKey).Result()
+		return err
+	})
+	if err == redis.Nil {
+		storage.observe(ctx, "FallbackToDefault", start, 0, nil)
+		return false, nil
+	}
 	if err != nil {
+		storage.observe(ctx, "FallbackToDefault", start, 0, err)
+		return false, wrap("FallbackToDefault", err)
+	}
+
+	storage.observe(ctx, "FallbackToDefault", start, len(result), nil)
+	return result == "1", nil
+}
+
+func (storage *Storage) SetFallbackToDefaultContext(ctx context.Context, enabled bool) (err error) {
+	start := time.Now()
+	defer func() { storage.observe(ctx, "SetFallbackToDefault", start, 1, err) }()
+
+	if err := ctx.Err(); err != nil {
+		return wrap("SetFallbackToDefault", err)
+	}
+
+	value := "0"
+	if enabled {
+		value = "1"
+	}
+
+	err = storage.client.Set(RedisFallbackToDefaultKey, value, 0).Err()
+	if err == nil {
+		storage.publish(&i18n.Change{Type: i18n.ChangeLanguages})
+	}
+
+	return wrap("SetFallbackToDefault", err)
+}
+
+func (storage *Storage) GetAllContext(ctx context.Context) ([]*i18n.Translation, error) {
+	start := time.Now()
+
+	var results []string
+	err := i18n.Await(ctx, func() (err error) {
+		results, err = storage.client.LRange(RedisKey, 0, -1).Result()
 		return err
+	})
+	if err != nil {
+		storage.observe(ctx, "GetAll", start, 0, err)
+		return nil, wrap("GetAll", err)
 	}
+	storage.observe(ctx, "GetAll", start, size(results), nil)
 
-	_, err = tx.Exec(func() error {
-		for i, result := range results {
-			if result == tag.String() {
-				tx.LSet(RedisSupportedLanguagesKey, int64(i), "~REMOVE~")
-			}
+	translations := make([]*i18n.Translation, 0, len(results))
+
+	for _, result := range results {
+		translation, err := decode(result)
+		if err != nil {
+			storage.log().Error("translation could not be decoded", "value", result, "error", err)
+			return nil, wrap("GetAll", err)
 		}
-		tx.LRem(RedisSupportedLanguagesKey, 0, "~REMOVE~")
-		return nil
-	})
 
-	if err == redis.TxFailedErr {
-		return storage.StoreSupportedLanguage(tag)
+		translations = append(translations, translation)
 	}
 
-	return err
+	return translations, nil
 }
 
-func (storage *Storage) SetDefaultLanguage(tag language.Tag) error {
-	return storage.client.Set(RedisDefaultLanguageKey, tag.String(), 0).Err()
-}
+// GetLanguageContext reads the hash of the language, until a write has built
+// the hashes every translation is read and filtered
+func (storage *Storage) GetLanguageContext(ctx context.Context, tag language.Tag) ([]*i18n.Translation, error) {
+	start := time.Now()
 
-func (storage *Storage) GetAll() ([]*i18n.Translation, error) {
-	cmd := storage.client.LRange(RedisKey, 0, -1)
-	results, err := cmd.Result()
+	var built bool
+	var results map[string]string
+	err := i18n.Await(ctx, func() (err error) {
+		built, err = indexed(storage.client)
+		if err != nil || !built {
+			return err
+		}
+		results, err = storage.client.HGetAllMap(languageKey(tag)).Result()
+		return err
+	})
 	if err != nil {
-		return nil, err
+		storage.observe(ctx, "GetLanguage", start, 0, err)
+		return nil, wrap("GetLanguage", err)
+	}
+
+	if !built {
+		storage.observe(ctx, "GetLanguage", start, 0, nil)
+
+		all, err := storage.GetAllContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var translations []*i18n.Translation
+		for _, translation := range all {
+			if translation.Lang.String() == tag.String() {
+				translations = append(translations, translation)
+			}
+		}
+		return translations, nil
 	}
 
+	bytes := 0
 	translations := make([]*i18n.Translation, 0, len(results))
 
 	for _, result := range results {
+		bytes += len(result)
+
 		translation, err := decode(result)
 		if err != nil {
-			return nil, err
+			storage.log().Error("translation could not be decoded", "value", result, "error", err)
+			storage.observe(ctx, "GetLanguage", start, bytes, err)
+			return nil, wrap("GetLanguage", err)
 		}
 
 		translations = append(translations, translation)
 	}
+	storage.observe(ctx, "GetLanguage", start, bytes, nil)
 
-	return translations, err
+	return translations, nil
 }
 
-func (storage *Storage) Store(t *i18n.Translation) error {
-	tx, err := storage.client.Watch(RedisKey)
-	if err != nil {
+// GetKeyContext reads a field of the hash of the language, until a write has
+// built the hashes the language is read and searched
+func (storage *Storage) GetKeyContext(ctx context.Context, tag language.Tag, key string) (*i18n.Translation, error) {
+	start := time.Now()
+
+	var built bool
+	var result string
+	err := i18n.Await(ctx, func() (err error) {
+		built, err = indexed(storage.client)
+		if err != nil || !built {
+			return err
+		}
+		result, err = storage.client.HGet(languageKey(tag), key).Result()
 		return err
+	})
+	if err == redis.Nil {
+		storage.observe(ctx, "GetKey", start, 0, nil)
+		return nil, wrap("GetKey", err)
+	}
+	if err != nil {
+		storage.observe(ctx, "GetKey", start, 0, err)
+		return nil, wrap("GetKey", err)
+	}
+
+	if !built {
+		storage.observe(ctx, "GetKey", start, 0, nil)
+
+		translations, err := storage.GetLanguageContext(ctx, tag)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, translation := range translations {
+			if translation.Key == key {
+				return translation, nil
+			}
+		}
+		return nil, i18n.ErrNotFound
 	}
-	defer tx.Close()
+	storage.observe(ctx, "GetKey", start, len(result), nil)
 
-	cmd := tx.LRange(RedisKey, 0, -1)
-	results, err := cmd.Result()
+	translation, err := decode(result)
 	if err != nil {
-		return err
+		storage.log().Error("translation could not be decoded", "value", result, "error", err)
+		return nil, wrap("GetKey", err)
 	}
 
-	_, err = tx.Exec(func() error {
-		for i, result := range results {
-			tr, err := decode(result)
+	return translation, nil
+}
+
+// GetAllInStateContext reads the hashes of the states, until a write has
+// built the hashes every translation is read and filtered
+func (storage *Storage) GetAllInStateContext(ctx context.Context, states ...i18n.State) ([]*i18n.Translation, error) {
+	start := time.Now()
+
+	var built bool
+	var results []string
+	err := i18n.Await(ctx, func() (err error) {
+		built, err = indexed(storage.client)
+		if err != nil || !built {
+			return err
+		}
+
+		seen := make(map[string]bool, len(states))
+		for _, state := range states {
+			k := stateKey(state)
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+
+			values, err := storage.client.HGetAllMap(k).Result()
 			if err != nil {
 				return err
 			}
-
-			if tr.Key == t.Key && tr.Lang.String() == t.Lang.String() {
-				tx.LSet(RedisKey, int64(i), "~REMOVE~")
+			for _, value := range values {
+				results = append(results, value)
 			}
 		}
-		tx.LRem(RedisKey, 0, "~REMOVE~")
-		tx.LPush(RedisKey, encode(t))
 		return nil
 	})
+	if err != nil {
+		storage.observe(ctx, "GetAllInState", start, 0, err)
+		return nil, wrap("GetAllInState", err)
+	}
+
+	if !built {
+		storage.observe(ctx, "GetAllInState", start, 0, nil)
 
-	if err == redis.TxFailedErr {
-		return storage.Store(t)
+		all, err := storage.GetAllContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return i18n.InState(all, states...), nil
 	}
 
-	return err
+	storage.observe(ctx, "GetAllInState", start, size(results), nil)
+
+	translations := make([]*i18n.Translation, 0, len(results))
+
+	for _, result := range results {
+		translation, err := decode(result)
+		if err != nil {
+			storage.log().Error("translation could not be decoded", "value", result, "error", err)
+			return nil, wrap("GetAllInState", err)
+		}
+
+		translations = append(translations, translation)
+	}
+
+	return translations, nil
 }
 
-func (storage *Storage) Delete(t *i18n.Translation) error {
-	tx, err := storage.client.Watch(RedisKey)
-	if err != nil {
-		return err
+func (storage *Storage) StoreContext(ctx context.Context, t *i18n.Translation) (err error) {
+	start := time.Now()
+	value := encode(t)
+	defer func() { storage.observe(ctx, "Store", start, len(value), err) }()
+
+	if err := ctx.Err(); err != nil {
+		return wrap("Store", err)
 	}
-	defer tx.Close()
 
-	cmd := tx.LRange(RedisKey, 0, -1)
-	results, err := cmd.Result()
-	if err != nil {
-		return err
+	err = storage.update(ctx, "Store", map[string]bool{key(t): true}, []string{value})
+
+	if err == nil {
+		storage.publish(&i18n.Change{Type: i18n.ChangeUpsert, Translation: t})
+	}
+
+	return wrap("Store", err)
+}
+
+func (storage *Storage) DeleteContext(ctx context.Context, t *i18n.Translation) (err error) {
+	start := time.Now()
+	defer func() { storage.observe(ctx, "Delete", start, len(t.Key), err) }()
+
+	if err := ctx.Err(); err != nil {
+		return wrap("Delete", err)
+	}
+
+	err = storage.update(ctx, "Delete", map[string]bool{key(t): true}, nil)
+
+	if err == nil {
+		storage.publish(&i18n.Change{Type: i18n.ChangeDelete, Translation: t})
+	}
+
+	return wrap("Delete", err)
+}
+
+// StoreMany adds or updates translations in a single transaction
+func (storage *Storage) StoreMany(translations []*i18n.Translation) error {
+	return storage.StoreManyContext(context.Background(), translations)
+}
+
+func (storage *Storage) StoreManyContext(ctx context.Context, translations []*i18n.Translation) (err error) {
+	if len(translations) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	values, replaced := encodeMany(translations)
+	defer func() { storage.observe(ctx, "StoreMany", start, size(values), err) }()
+
+	if err := ctx.Err(); err != nil {
+		return wrap("StoreMany", err)
+	}
+
+	err = storage.update(ctx, "StoreMany", replaced, values)
+
+	if err == nil {
+		storage.publish(i18n.ChangesFor(i18n.ChangeUpsert, translations)...)
+	}
+
+	return wrap("StoreMany", err)
+}
+
+// DeleteMany removes translations in a single transaction
+func (storage *Storage) DeleteMany(translations []*i18n.Translation) error {
+	return storage.DeleteManyContext(context.Background(), translations)
+}
+
+func (storage *Storage) DeleteManyContext(ctx context.Context, translations []*i18n.Translation) (err error) {
+	if len(translations) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	bytes := 0
+
+	remove := make(map[string]bool, len(translations))
+	for _, t := range translations {
+		remove[key(t)] = true
+		bytes += len(t.Key)
+	}
+
+	defer func() { storage.observe(ctx, "DeleteMany", start, bytes, err) }()
+
+	if err := ctx.Err(); err != nil {
+		return wrap("DeleteMany", err)
+	}
+
+	err = storage.update(ctx, "DeleteMany", remove, nil)
+
+	if err == nil {
+		storage.publish(i18n.ChangesFor(i18n.ChangeDelete, translations)...)
+	}
+
+	return wrap("DeleteMany", err)
+}
+
+// Move stores some translations and deletes others in a single transaction,
+// translations in both are stored
+func (storage *Storage) Move(store []*i18n.Translation, remove []*i18n.Translation) error {
+	return storage.MoveContext(context.Background(), store, remove)
+}
+
+func (storage *Storage) MoveContext(ctx context.Context, store []*i18n.Translation, remove []*i18n.Translation) (err error) {
+	if len(store) == 0 && len(remove) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	values, replaced := encodeMany(store)
+	defer func() { storage.observe(ctx, "Move", start, size(values), err) }()
+
+	if err := ctx.Err(); err != nil {
+		return wrap("Move", err)
+	}
+
+	var deleted []*i18n.Translation
+	keys := make(map[string]bool, len(replaced)+len(remove))
+	for k := range replaced {
+		keys[k] = true
+	}
+	for _, t := range remove {
+		if !replaced[key(t)] {
+			keys[key(t)] = true
+			deleted = append(deleted, t)
+		}
+	}
+
+	err = storage.update(ctx, "Move", keys, values)
+
+	if err == nil {
+		storage.publish(i18n.ChangesFor(i18n.ChangeUpsert, store)...)
+		storage.publish(i18n.ChangesFor(i18n.ChangeDelete, deleted)...)
 	}
 
-	_, err = tx.Exec(func() error {
-		for i, result := range results {
+	return wrap("Move", err)
+}
+
+// ReplaceAll replaces every stored translation in a single transaction
+func (storage *Storage) ReplaceAll(translations []*i18n.Translation) error {
+	return storage.ReplaceAllContext(context.Background(), translations)
+}
+
+func (storage *Storage) ReplaceAllContext(ctx context.Context, translations []*i18n.Translation) (err error) {
+	start := time.Now()
+	values, _ := encodeMany(translations)
+	defer func() { storage.observe(ctx, "ReplaceAll", start, size(values), err) }()
+
+	if err := ctx.Err(); err != nil {
+		return wrap("ReplaceAll", err)
+	}
+
+	err = storage.retry(ctx, "ReplaceAll", func() error {
+		tx, err := storage.client.Watch(RedisKey, RedisIndexedKey)
+		if err != nil {
+			return err
+		}
+		defer tx.Close()
+
+		results, err := tx.LRange(RedisKey, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+
+		// The hashes of the languages and states stored now are dropped with
+		// the list
+		remove := []string{RedisKey}
+		seen := make(map[string]bool)
+		for _, result := range results {
 			tr, err := decode(result)
 			if err != nil {
 				return err
 			}
 
-			if tr.Key == t.Key && tr.Lang.String() == t.Lang.String() {
-				tx.LSet(RedisKey, int64(i), "~REMOVE~")
+			for _, k := range []string{languageKey(tr.Lang), stateKey(tr.State)} {
+				if !seen[k] {
+					seen[k] = true
+					remove = append(remove, k)
+				}
 			}
 		}
-		tx.LRem(RedisKey, 0, "~REMOVE~")
-		return nil
+
+		_, err = tx.Exec(func() error {
+			tx.Del(remove...)
+			if len(values) > 0 {
+				tx.LPush(RedisKey, values...)
+			}
+			return index(tx, values)
+		})
+		return err
 	})
 
-	if err == redis.TxFailedErr {
-		return storage.Delete(t)
+	if err == nil {
+		storage.publish(&i18n.Change{Type: i18n.ChangeReload})
 	}
 
-	return err
+	return wrap("ReplaceAll", err)
+}
+
+// update runs a transaction removing the stored translations with keys in
+// remove and pushing values, the language hashes are kept in step or built
+// if they have not been yet
+func (storage *Storage) update(ctx context.Context, op string, remove map[string]bool, values []string) error {
+	return storage.retry(ctx, op, func() error {
+		tx, err := storage.client.Watch(RedisKey, RedisIndexedKey)
+		if err != nil {
+			return err
+		}
+		defer tx.Close()
+
+		results, err := tx.LRange(RedisKey, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+
+		built, err := indexed(tx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(func() error {
+			var kept []string
+
+			for i, result := range results {
+				tr, err := decode(result)
+				if err != nil {
+					return err
+				}
+
+				if !remove[key(tr)] {
+					kept = append(kept, result)
+					continue
+				}
+
+				tx.LSet(RedisKey, int64(i), "~REMOVE~")
+				if built {
+					tx.HDel(languageKey(tr.Lang), tr.Key)
+					tx.HDel(stateKey(tr.State), key(tr))
+				}
+			}
+			tx.LRem(RedisKey, 0, "~REMOVE~")
+			if len(values) > 0 {
+				tx.LPush(RedisKey, values...)
+			}
+
+			if built {
+				return index(tx, values)
+			}
+			return index(tx, append(kept, values...))
+		})
+		return err
+	})
+}
+
+// index adds encoded translations to the hashes of their languages and states
+// and marks the hashes as built, it is queued in a transaction
+func index(tx *redis.Multi, values []string) error {
+	for _, value := range values {
+		tr, err := decode(value)
+		if err != nil {
+			return err
+		}
+		tx.HSet(languageKey(tr.Lang), tr.Key, value)
+		tx.HSet(stateKey(tr.State), key(tr), value)
+	}
+
+	tx.Set(RedisIndexedKey, "1", 0)
+	return nil
+}
+
+// publish notifies watchers of changes, watchers that miss a change are
+// corrected by their next sync
+func (storage *Storage) publish(changes ...*i18n.Change) {
+	for _, change := range changes {
+		storage.client.Publish(RedisChangeChannel, encodeChange(change))
+	}
+}
+
+// Watch subscribes to changes published by every Storage using the same redis
+// server. Receiving is retried with a backoff after a failure, then a
+// ChangeReload is sent as changes published in the meantime are lost.
+func (storage *Storage) Watch(stop <-chan struct{}) (<-chan *i18n.Change, error) {
+	pubsub, err := storage.client.Subscribe(RedisChangeChannel)
+	if err != nil {
+		return nil, wrap("Watch", err)
+	}
+
+	changes := make(chan *i18n.Change)
+
+	go func() {
+		<-stop
+		pubsub.Close()
+	}()
+
+	go func() {
+		defer close(changes)
+
+		failures := 0
+
+		for {
+			msg, err := pubsub.ReceiveMessage()
+			if err != nil {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				failures++
+				wait := i18n.Backoff(failures, watchMinBackoff, watchMaxBackoff)
+				storage.log().Warn("receiving changes failed", "failures", failures, "retry", wait, "error", err)
+
+				select {
+				case <-stop:
+					return
+				case <-time.After(wait):
+					continue
+				}
+			}
+
+			// Changes published while disconnected are lost, so everything
+			// is reloaded once messages are received again
+			if failures > 0 {
+				failures = 0
+				select {
+				case changes <- &i18n.Change{Type: i18n.ChangeReload}:
+				case <-stop:
+					return
+				}
+			}
+
+			change, err := decodeChange(msg.Payload)
+			if err != nil {
+				storage.log().Warn("change could not be decoded", "payload", msg.Payload, "error", err)
+				continue
+			}
+
+			select {
+			case changes <- change:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return changes, nil
 }
@@ -1,9 +1,13 @@
 package redis
 
 import (
+	"errors"
 	"fmt"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"golang.org/x/net/context"
 	"golang.org/x/text/language"
 
 	"github.com/ThatsMrTalbot/i18n"
@@ -42,6 +46,40 @@ func TestRedisStorage(t *testing.T) {
 			})
 		})
 
+		Convey("When the fallback chain is set", func() {
+			storage.StoreFallback(&i18n.Fallback{
+				Lang:  language.BrazilianPortuguese,
+				Chain: []language.Tag{language.Portuguese, language.Spanish},
+			})
+			storage.StoreFallback(&i18n.Fallback{
+				Lang:  language.BrazilianPortuguese,
+				Chain: []language.Tag{language.Spanish},
+			})
+			storage.StoreFallback(&i18n.Fallback{
+				Lang:  language.CanadianFrench,
+				Chain: []language.Tag{language.French},
+			})
+			storage.DeleteFallback(language.CanadianFrench)
+
+			Convey("Then the fallback chain should be correct", func() {
+				fallbacks, err := storage.Fallbacks()
+				So(err, ShouldBeNil)
+				So(fallbacks, ShouldHaveLength, 1)
+				So(fallbacks[0].Lang.String(), ShouldEqual, language.BrazilianPortuguese.String())
+				So(fallbacks[0].Chain, ShouldResemble, []language.Tag{language.Spanish})
+			})
+		})
+
+		Convey("When falling back to the default language is enabled", func() {
+			storage.SetFallbackToDefault(true)
+
+			Convey("Then the setting should be stored", func() {
+				enabled, err := storage.FallbackToDefault()
+				So(err, ShouldBeNil)
+				So(enabled, ShouldBeTrue)
+			})
+		})
+
 		Convey("When an item is added to the memory store", func() {
 
 			expected := &i18n.Translation{
@@ -93,6 +131,316 @@ func TestRedisStorage(t *testing.T) {
 				So(results[0], ShouldResemble, replacement)
 			})
 		})
+
+		Convey("When an item with plural variants is added to the memory store", func() {
+
+			expected := &i18n.Translation{
+				Lang:  language.English,
+				Key:   "SomeKey",
+				Value: "SomeValue",
+				Plurals: map[i18n.PluralForm]string{
+					i18n.PluralOne:   "SomeValue",
+					i18n.PluralOther: "SomeValues",
+				},
+			}
+
+			err := storage.Store(expected)
+			So(err, ShouldBeNil)
+
+			Convey("Then the variants should be accessable", func() {
+				results, err := storage.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0], ShouldResemble, expected)
+			})
+		})
+
+		Convey("When an item with a state and metadata is added to the memory store", func() {
+
+			expected := &i18n.Translation{
+				Lang:  language.English,
+				Key:   "SomeKey",
+				Value: "SomeValue",
+				State: i18n.StateApproved,
+				Metadata: &i18n.Metadata{
+					Description: "Shown on the checkout button",
+					Context:     "verb",
+					MaxLength:   20,
+					Labels:      []string{"web", "checkout"},
+					Created:     time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC),
+					Updated:     time.Date(2016, 2, 3, 4, 5, 6, 0, time.UTC),
+					Author:      "alice",
+					ReviewedBy:  "bob",
+					Reviewed:    time.Date(2016, 2, 4, 5, 6, 7, 0, time.UTC),
+				},
+			}
+
+			err := storage.Store(expected)
+			So(err, ShouldBeNil)
+
+			Convey("Then the state and metadata should be accessable", func() {
+				results, err := storage.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0], ShouldResemble, expected)
+			})
+		})
+	})
+
+	Convey("Given a cancelled context", t, func() {
+		storage, err := Connect("127.0.0.1:6379", "", 0)
+		So(err, ShouldBeNil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		Convey("When the storage is used", func() {
+			_, readErr := storage.GetAllContext(ctx)
+			writeErr := storage.StoreContext(ctx, &i18n.Translation{
+				Lang:  language.English,
+				Key:   "SomeKey",
+				Value: "SomeValue",
+			})
+
+			Convey("Then the context error should be returned", func() {
+				So(errors.Is(readErr, context.Canceled), ShouldBeTrue)
+				So(errors.Is(writeErr, context.Canceled), ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("Given an observed storage", t, func() {
+		storage, err := Connect("127.0.0.1:6379", "", 0)
+		So(err, ShouldBeNil)
+
+		metrics := i18n.NewMetrics()
+		storage.SetObserver(metrics)
+
+		Convey("When the storage is used", func() {
+			storage.Store(&i18n.Translation{
+				Lang:  language.English,
+				Key:   "ObservedKey",
+				Value: "ObservedValue",
+			})
+			storage.GetAll()
+
+			Convey("Then the operations should be observed", func() {
+				w := httptest.NewRecorder()
+				metrics.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+				So(w.Body.String(), ShouldContainSubstring, "i18n_storage_operations_total{backend=\"redis\",op=\"Store\"} 1\n")
+				So(w.Body.String(), ShouldContainSubstring, "i18n_storage_operations_total{backend=\"redis\",op=\"GetAll\"} 1\n")
+				So(w.Body.String(), ShouldNotContainSubstring, "i18n_storage_bytes_total{backend=\"redis\",op=\"GetAll\"} 0\n")
+			})
+		})
+
+		Reset(func() {
+			storage.Delete(&i18n.Translation{
+				Lang: language.English,
+				Key:  "ObservedKey",
+			})
+		})
+	})
+
+	Convey("Given an empty storage", t, func() {
+		storage, err := Connect("127.0.0.1:6379", "", 0)
+		So(err, ShouldBeNil)
+
+		Convey("When many items are added to the storage", func() {
+			err := storage.StoreMany([]*i18n.Translation{
+				{Lang: language.English, Key: "SomeKey", Value: "SomeValue"},
+				{Lang: language.English, Key: "OtherKey", Value: "OtherValue"},
+				{Lang: language.English, Key: "SomeKey", Value: "SomeOtherValue"},
+			})
+			So(err, ShouldBeNil)
+
+			Convey("Then each key should exist once with the last value", func() {
+				results, err := storage.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 2)
+
+				for _, result := range results {
+					if result.Key == "SomeKey" {
+						So(result.Value, ShouldEqual, "SomeOtherValue")
+					}
+				}
+			})
+
+			Convey("Then deleting many should remove them", func() {
+				err := storage.DeleteMany([]*i18n.Translation{
+					{Lang: language.English, Key: "SomeKey"},
+				})
+				So(err, ShouldBeNil)
+
+				results, err := storage.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Key, ShouldEqual, "OtherKey")
+			})
+
+			Convey("Then replacing all should leave only the replacements", func() {
+				err := storage.ReplaceAll([]*i18n.Translation{
+					{Lang: language.Spanish, Key: "SomeKey", Value: "SomeValue"},
+				})
+				So(err, ShouldBeNil)
+
+				results, err := storage.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Lang.String(), ShouldEqual, language.Spanish.String())
+
+				results, err = storage.GetLanguage(language.English)
+				So(err, ShouldBeNil)
+				So(results, ShouldBeEmpty)
+
+				results, err = storage.GetAllInState(i18n.StateApproved)
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+			})
+
+			Convey("Then moving should store and delete in one transaction", func() {
+				err := storage.Move(
+					[]*i18n.Translation{{Lang: language.English, Key: "NewKey", Value: "SomeOtherValue"}},
+					[]*i18n.Translation{{Lang: language.English, Key: "SomeKey"}},
+				)
+				So(err, ShouldBeNil)
+
+				results, err := storage.GetLanguage(language.English)
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 2)
+				for _, result := range results {
+					So(result.Key, ShouldNotEqual, "SomeKey")
+				}
+			})
+
+			Convey("Then a single key should be readable", func() {
+				translation, err := storage.GetKey(language.English, "OtherKey")
+				So(err, ShouldBeNil)
+				So(translation.Value, ShouldEqual, "OtherValue")
+
+				_, err = storage.GetKey(language.French, "OtherKey")
+				So(errors.Is(err, i18n.ErrNotFound), ShouldBeTrue)
+			})
+
+			Convey("Then a single language should be readable", func() {
+				So(storage.Store(&i18n.Translation{Lang: language.French, Key: "SomeKey", Value: "UneValeur"}), ShouldBeNil)
+				So(storage.Delete(&i18n.Translation{Lang: language.English, Key: "OtherKey"}), ShouldBeNil)
+
+				results, err := storage.GetLanguage(language.English)
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Value, ShouldEqual, "SomeOtherValue")
+
+				results, err = storage.GetLanguage(language.French)
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Value, ShouldEqual, "UneValeur")
+			})
+
+			Convey("Then translations in a state should be readable", func() {
+				So(storage.Store(&i18n.Translation{
+					Lang:     language.English,
+					Key:      "SomeKey",
+					Value:    "ADraftValue",
+					State:    i18n.StateDraft,
+					Approved: &i18n.Translation{Lang: language.English, Key: "SomeKey", Value: "SomeValue"},
+				}), ShouldBeNil)
+
+				results, err := storage.GetAllInState(i18n.StateDraft)
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Value, ShouldEqual, "ADraftValue")
+				So(results[0].Approved, ShouldNotBeNil)
+				So(results[0].Approved.Value, ShouldEqual, "SomeValue")
+
+				results, err = storage.GetAllInState(i18n.StateApproved)
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Key, ShouldEqual, "OtherKey")
+			})
+		})
+
+		Convey("When translations were stored before the language hashes", func() {
+			storage.client.Del(RedisIndexedKey)
+			storage.client.LPush(RedisKey, encode(&i18n.Translation{Lang: language.English, Key: "SomeKey", Value: "SomeValue"}))
+
+			Convey("Then a single language should be read from the list", func() {
+				results, err := storage.GetLanguage(language.English)
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+
+				results, err = storage.GetLanguage(language.French)
+				So(err, ShouldBeNil)
+				So(results, ShouldBeEmpty)
+			})
+
+			Convey("Then translations in a state should be read from the list", func() {
+				results, err := storage.GetAllInState(i18n.StateApproved)
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+
+				results, err = storage.GetAllInState(i18n.StateDraft)
+				So(err, ShouldBeNil)
+				So(results, ShouldBeEmpty)
+			})
+
+			Convey("Then the next write should build the hashes", func() {
+				So(storage.Store(&i18n.Translation{Lang: language.French, Key: "SomeKey", Value: "UneValeur"}), ShouldBeNil)
+
+				built, err := indexed(storage.client)
+				So(err, ShouldBeNil)
+				So(built, ShouldBeTrue)
+
+				results, err := storage.GetLanguage(language.English)
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Value, ShouldEqual, "SomeValue")
+			})
+		})
+
+		Reset(func() {
+			storage.ReplaceAll(nil)
+		})
+	})
+
+	Convey("Given a watched storage", t, func() {
+		storage, err := Connect("127.0.0.1:6379", "", 0)
+		So(err, ShouldBeNil)
+
+		other, err := Connect("127.0.0.1:6379", "", 0)
+		So(err, ShouldBeNil)
+
+		stop := make(chan struct{})
+		changes, err := storage.Watch(stop)
+		So(err, ShouldBeNil)
+
+		Convey("When an item is added through another storage", func() {
+			expected := &i18n.Translation{
+				Lang:  language.English,
+				Key:   "SomeKey",
+				Value: "SomeValue",
+			}
+
+			err := other.Store(expected)
+			So(err, ShouldBeNil)
+
+			Convey("Then the change should be received", func() {
+				var change *i18n.Change
+				select {
+				case change = <-changes:
+				case <-time.After(time.Second):
+				}
+
+				So(change, ShouldNotBeNil)
+				So(change.Type, ShouldEqual, i18n.ChangeUpsert)
+				So(change.Translation, ShouldResemble, expected)
+			})
+		})
+
+		Reset(func() {
+			close(stop)
+			storage.ReplaceAll(nil)
+		})
 	})
 
 	Convey("Given a populated storage", t, func() {
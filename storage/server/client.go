@@ -1,15 +1,20 @@
 package server
 
 import (
-	"errors"
+	"encoding/json"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 
+	"golang.org/x/net/context"
 	"golang.org/x/text/language"
 
 	"github.com/ThatsMrTalbot/i18n"
+	"github.com/ThatsMrTalbot/i18n/internal/codec"
 )
 
 type RequestMiddleware func(r *http.Request)
@@ -18,12 +23,23 @@ type Storage struct {
 	url string
 
 	middleware []RequestMiddleware
+	client     *http.Client
+	observer   i18n.Observer
+	logger     i18n.Logger
 
-	lock           sync.Mutex
-	updated        time.Time
+	lock    sync.Mutex
+	updated time.Time
+	fetched fetched
+}
+
+// fetched is the catalog last fetched from the server, it is replaced rather
+// than changed so copies can be read without the lock
+type fetched struct {
 	translations   []*i18n.Translation
 	supportedLangs []language.Tag
 	defaultLang    language.Tag
+	fallbacks      []*i18n.Fallback
+	toDefault      bool
 }
 
 func NewStorage(url string, middleware ...RequestMiddleware) *Storage {
@@ -33,88 +49,490 @@ func NewStorage(url string, middleware ...RequestMiddleware) *Storage {
 	}
 }
 
-func (storage *Storage) sync() error {
+// SetClient sets the client requests are made with, it must be set before the
+// storage is used. Watch streams use the same client, so a client Timeout ends
+// a stream and it is reconnected.
+func (storage *Storage) SetClient(client *http.Client) {
+	storage.client = client
+}
+
+func (storage *Storage) httpClient() *http.Client {
+	if storage.client != nil {
+		return storage.client
+	}
+	return http.DefaultClient
+}
+
+// SetObserver sets the observer notified of every request made to the server,
+// it must be set before the storage is used
+func (storage *Storage) SetObserver(observer i18n.Observer) {
+	storage.observer = observer
+}
+
+// SetLogger sets the logger for requests made to the server and decode
+// failures, it must be set before the storage is used
+func (storage *Storage) SetLogger(logger i18n.Logger) {
+	storage.logger = logger
+}
+
+func (storage *Storage) log() i18n.Logger {
+	return logger(storage.logger)
+}
+
+// logger gets a logger that discards events if none is set
+func logger(l i18n.Logger) i18n.Logger {
+	if l != nil {
+		return l
+	}
+	return i18n.NopLogger
+}
+
+// observe reports an operation to an observer if one is set
+func observe(observer i18n.Observer, ctx context.Context, op string, start time.Time, bytes int, err error) {
+	if observer == nil {
+		return
+	}
+
+	observer.ObserveStorage(ctx, &i18n.StorageEvent{
+		Backend:  "server",
+		Op:       op,
+		Start:    start,
+		Duration: time.Since(start),
+		Bytes:    bytes,
+		Err:      err,
+	})
+}
+
+// sync fetches the catalog from the server if it is more than a minute old
+// and gets a copy of it taken while holding the lock, as the watch may start
+// another fetch once it is released. The request is cancelled if the context
+// is done.
+func (storage *Storage) sync(ctx context.Context) (fetched, error) {
 	storage.lock.Lock()
 	defer storage.lock.Unlock()
 
 	now := time.Now()
 	if now.Sub(storage.updated) > 1*time.Minute {
-		client := &http.Client{}
-		req, err := http.NewRequest("GET", storage.url, nil)
-		if err != nil {
-			return err
-		}
+		storage.log().Debug("fetching translations", "url", storage.url)
 
-		for _, middleware := range storage.middleware {
-			middleware(req)
-		}
-
-		resp, err := client.Do(req)
+		body, err := storage.fetch(ctx, storage.url)
+		observe(storage.observer, ctx, "sync", now, len(body), err)
 		if err != nil {
-			return err
+			storage.log().Error("fetching translations failed", "url", storage.url, "error", err)
+			return fetched{}, err
 		}
 
-		body, err := ioutil.ReadAll(resp.Body)
+		t, s, d, f, toDefault, err := decode(body)
 		if err != nil {
-			return err
+			storage.log().Error("translations could not be decoded", "url", storage.url, "bytes", len(body), "error", err)
+			return fetched{}, wrap("decode", err)
 		}
 
-		t, s, d, err := decode(body)
-		if err != nil {
-			return err
-		}
+		storage.log().Debug("fetched translations", "url", storage.url, "bytes", len(body), "duration", time.Since(now))
 
-		storage.translations = t
-		storage.supportedLangs = s
-		storage.defaultLang = d
+		storage.fetched = fetched{
+			translations:   t,
+			supportedLangs: s,
+			defaultLang:    d,
+			fallbacks:      f,
+			toDefault:      toDefault,
+		}
 		storage.updated = now
 	}
-	return nil
+	return storage.fetched, nil
+}
+
+// fetch gets the encoded catalog from the server
+func (storage *Storage) fetch(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, wrap("sync", err)
+	}
+
+	req = req.WithContext(ctx)
+
+	for _, middleware := range storage.middleware {
+		middleware(req)
+	}
+
+	resp, err := storage.httpClient().Do(req)
+	if err != nil {
+		return nil, wrap("sync", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, wrap("sync", statusError(resp.StatusCode))
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, wrap("sync", err)
+	}
+
+	return body, nil
+}
+
+// Capabilities reports the storage as read only, writes must be made to the
+// storage behind the server
+func (storage *Storage) Capabilities() i18n.Capabilities {
+	return i18n.Capabilities{}
 }
 
 func (storage *Storage) GetAll() ([]*i18n.Translation, error) {
-	err := storage.sync()
+	return storage.GetAllContext(context.Background())
+}
+
+// GetAllContext gets the translations, fetching them from the server with the
+// context if they are out of date
+func (storage *Storage) GetAllContext(ctx context.Context) ([]*i18n.Translation, error) {
+	f, err := storage.sync(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return storage.translations, err
+	return append([]*i18n.Translation(nil), f.translations...), nil
+}
+
+// GetLanguage gets the translations of a single language
+func (storage *Storage) GetLanguage(tag language.Tag) ([]*i18n.Translation, error) {
+	return storage.GetLanguageContext(context.Background(), tag)
+}
+
+// GetLanguageContext filters the fetched translations if they are up to date,
+// otherwise only the language is fetched from the server and nothing is kept
+func (storage *Storage) GetLanguageContext(ctx context.Context, tag language.Tag) ([]*i18n.Translation, error) {
+	cached, fresh := storage.cached()
+	if !fresh {
+		return storage.query(ctx, "GetLanguage", "lang", tag.String())
+	}
+
+	var translations []*i18n.Translation
+	for _, translation := range cached {
+		if translation.Lang.String() == tag.String() {
+			translations = append(translations, translation)
+		}
+	}
+
+	return translations, nil
+}
+
+// GetAllInState gets the translations in any of the states given
+func (storage *Storage) GetAllInState(states ...i18n.State) ([]*i18n.Translation, error) {
+	return storage.GetAllInStateContext(context.Background(), states...)
+}
+
+// GetAllInStateContext filters the fetched translations if they are up to
+// date, otherwise only the states are fetched from the server and nothing is
+// kept
+func (storage *Storage) GetAllInStateContext(ctx context.Context, states ...i18n.State) ([]*i18n.Translation, error) {
+	cached, fresh := storage.cached()
+	if fresh {
+		return i18n.InState(cached, states...), nil
+	}
+
+	names := make([]string, len(states))
+	for i, state := range states {
+		if state == "" {
+			state = i18n.StateApproved
+		}
+		names[i] = string(state)
+	}
+
+	return storage.query(ctx, "GetAllInState", "state", strings.Join(names, ","))
+}
+
+// cached gets the fetched translations and whether they are up to date
+func (storage *Storage) cached() ([]*i18n.Translation, bool) {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
+	return storage.fetched.translations, time.Since(storage.updated) <= 1*time.Minute
+}
+
+// query fetches the translations matching a query parameter from the server
+// without keeping them
+func (storage *Storage) query(ctx context.Context, op string, param string, value string) ([]*i18n.Translation, error) {
+	u, err := url.Parse(storage.url)
+	if err != nil {
+		return nil, wrap(op, err)
+	}
+
+	query := u.Query()
+	query.Set(param, value)
+	u.RawQuery = query.Encode()
+
+	start := time.Now()
+	body, err := storage.fetch(ctx, u.String())
+	observe(storage.observer, ctx, op, start, len(body), err)
+	if err != nil {
+		storage.log().Error("fetching translations failed", "url", storage.url, param, value, "error", err)
+		return nil, err
+	}
+
+	translations, _, _, _, _, err := decode(body)
+	if err != nil {
+		storage.log().Error("translations could not be decoded", "url", storage.url, "bytes", len(body), "error", err)
+		return nil, wrap("decode", err)
+	}
+
+	return translations, nil
 }
 
 func (storage *Storage) Store(t *i18n.Translation) error {
-	return errors.New("Not implemented")
+	return readOnly("Store")
+}
+
+func (storage *Storage) StoreContext(ctx context.Context, t *i18n.Translation) error {
+	return readOnly("Store")
 }
 
 func (storage *Storage) Delete(t *i18n.Translation) error {
-	return errors.New("Not implemented")
+	return readOnly("Delete")
+}
+
+func (storage *Storage) DeleteContext(ctx context.Context, t *i18n.Translation) error {
+	return readOnly("Delete")
 }
 
 func (storage *Storage) DefaultLanguage() (language.Tag, error) {
-	err := storage.sync()
+	return storage.DefaultLanguageContext(context.Background())
+}
+
+func (storage *Storage) DefaultLanguageContext(ctx context.Context) (language.Tag, error) {
+	f, err := storage.sync(ctx)
 	if err != nil {
 		return language.Und, err
 	}
 
-	return storage.defaultLang, err
+	return f.defaultLang, nil
 }
 
 func (storage *Storage) SupportedLanguages() ([]language.Tag, error) {
-	err := storage.sync()
+	return storage.SupportedLanguagesContext(context.Background())
+}
+
+func (storage *Storage) SupportedLanguagesContext(ctx context.Context) ([]language.Tag, error) {
+	f, err := storage.sync(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return storage.supportedLangs, err
+	return append([]language.Tag(nil), f.supportedLangs...), nil
 }
 
 func (storage *Storage) SetDefaultLanguage(language.Tag) error {
-	return errors.New("Not implemented")
+	return readOnly("SetDefaultLanguage")
+}
+
+func (storage *Storage) SetDefaultLanguageContext(context.Context, language.Tag) error {
+	return readOnly("SetDefaultLanguage")
 }
 
 func (storage *Storage) StoreSupportedLanguage(language.Tag) error {
-	return errors.New("Not implemented")
+	return readOnly("StoreSupportedLanguage")
+}
+
+func (storage *Storage) StoreSupportedLanguageContext(context.Context, language.Tag) error {
+	return readOnly("StoreSupportedLanguage")
 }
 
 func (storage *Storage) DeleteSupportedLanguage(language.Tag) error {
-	return errors.New("Not implemented")
+	return readOnly("DeleteSupportedLanguage")
+}
+
+func (storage *Storage) DeleteSupportedLanguageContext(context.Context, language.Tag) error {
+	return readOnly("DeleteSupportedLanguage")
+}
+
+func (storage *Storage) Fallbacks() ([]*i18n.Fallback, error) {
+	return storage.FallbacksContext(context.Background())
+}
+
+func (storage *Storage) FallbacksContext(ctx context.Context) ([]*i18n.Fallback, error) {
+	f, err := storage.sync(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]*i18n.Fallback(nil), f.fallbacks...), nil
+}
+
+func (storage *Storage) StoreFallback(*i18n.Fallback) error {
+	return readOnly("StoreFallback")
+}
+
+func (storage *Storage) StoreFallbackContext(context.Context, *i18n.Fallback) error {
+	return readOnly("StoreFallback")
+}
+
+func (storage *Storage) DeleteFallback(language.Tag) error {
+	return readOnly("DeleteFallback")
+}
+
+func (storage *Storage) DeleteFallbackContext(context.Context, language.Tag) error {
+	return readOnly("DeleteFallback")
+}
+
+func (storage *Storage) FallbackToDefault() (bool, error) {
+	return storage.FallbackToDefaultContext(context.Background())
+}
+
+func (storage *Storage) FallbackToDefaultContext(ctx context.Context) (bool, error) {
+	f, err := storage.sync(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return f.toDefault, nil
+}
+
+func (storage *Storage) SetFallbackToDefault(bool) error {
+	return readOnly("SetFallbackToDefault")
+}
+
+func (storage *Storage) SetFallbackToDefaultContext(context.Context, bool) error {
+	return readOnly("SetFallbackToDefault")
+}
+
+// Watch retries connecting to the server after a failure with a backoff
+// between these bounds
+const (
+	watchMinBackoff = time.Second
+	watchMaxBackoff = time.Minute
+)
+
+// Watch streams changes from the server, the server storage must be a
+// i18n.WatchableStorage. Reads made after a change fetch the catalog again.
+// The stream is reconnected with a backoff if it ends, then a ChangeReload is
+// sent as changes made in the meantime are lost.
+func (storage *Storage) Watch(stop <-chan struct{}) (<-chan *i18n.Change, error) {
+	u, err := url.Parse(storage.url)
+	if err != nil {
+		return nil, wrap("Watch", err)
+	}
+
+	query := u.Query()
+	query.Set("watch", "1")
+	u.RawQuery = query.Encode()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	body, err := storage.stream(ctx, u.String())
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	changes := make(chan *i18n.Change)
+
+	go func() {
+		defer close(changes)
+
+		failures := 0
+
+		for {
+			received, err := storage.receive(body, changes, stop)
+			body.Close()
+
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			if received {
+				failures = 0
+			}
+			storage.log().Warn("watch stream ended", "url", storage.url, "error", err)
+
+			for {
+				failures++
+				wait := i18n.Backoff(failures, watchMinBackoff, watchMaxBackoff)
+
+				select {
+				case <-stop:
+					return
+				case <-time.After(wait):
+				}
+
+				body, err = storage.stream(ctx, u.String())
+				if err == nil {
+					break
+				}
+				storage.log().Warn("reconnecting watch failed", "url", storage.url, "failures", failures, "error", err)
+			}
+
+			storage.invalidate()
+
+			select {
+			case changes <- &i18n.Change{Type: i18n.ChangeReload}:
+			case <-stop:
+				body.Close()
+				return
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+// stream opens the change stream, it is closed when the context is done
+func (storage *Storage) stream(ctx context.Context, u string) (io.ReadCloser, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, wrap("Watch", err)
+	}
+	req = req.WithContext(ctx)
+
+	for _, middleware := range storage.middleware {
+		middleware(req)
+	}
+
+	resp, err := storage.httpClient().Do(req)
+	if err != nil {
+		return nil, wrap("Watch", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, wrap("Watch", statusError(resp.StatusCode))
+	}
+
+	return resp.Body, nil
+}
+
+// receive sends changes read from a stream until it ends or stop is closed,
+// it reports whether any change was received
+func (storage *Storage) receive(body io.Reader, changes chan<- *i18n.Change, stop <-chan struct{}) (bool, error) {
+	decoder := json.NewDecoder(body)
+	received := false
+
+	for {
+		var obj codec.Change
+		if err := decoder.Decode(&obj); err != nil {
+			return received, err
+		}
+		received = true
+
+		storage.invalidate()
+
+		select {
+		case changes <- codec.DecodeChange(&obj):
+		case <-stop:
+			return received, nil
+		}
+	}
+}
+
+// invalidate makes the next read fetch the catalog again
+func (storage *Storage) invalidate() {
+	storage.lock.Lock()
+	storage.updated = time.Time{}
+	storage.lock.Unlock()
 }
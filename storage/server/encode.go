@@ -6,53 +6,58 @@ import (
 	"golang.org/x/text/language"
 
 	"github.com/ThatsMrTalbot/i18n"
+	"github.com/ThatsMrTalbot/i18n/internal/codec"
 )
 
-type translationObject struct {
-	Lang  string `json:"lang"`
-	Key   string `json:"key"`
-	Value string `json:"value"`
-}
-
 type payload struct {
 	DefaultLanguage    string               `json:"default"`
 	SupportedLanguages []string             `json:"supported"`
-	Translations       []*translationObject `json:"translations"`
+	Translations       []*codec.Translation `json:"translations"`
+	Fallbacks          map[string][]string  `json:"fallbacks,omitempty"`
+	FallbackToDefault  bool                 `json:"fallback_to_default,omitempty"`
 }
 
-func encode(translations []*i18n.Translation, supported []language.Tag, defaultLang language.Tag) []byte {
+func encode(translations []*i18n.Translation, supported []language.Tag, defaultLang language.Tag, fallbacks []*i18n.Fallback, toDefault bool) []byte {
 	s := make([]string, 0, len(supported))
 
 	for _, i := range supported {
 		s = append(s, i.String())
 	}
 
-	objs := make([]*translationObject, 0, len(translations))
+	f := make(map[string][]string, len(fallbacks))
+
+	for _, i := range fallbacks {
+		chain := make([]string, 0, len(i.Chain))
+		for _, tag := range i.Chain {
+			chain = append(chain, tag.String())
+		}
+		f[i.Lang.String()] = chain
+	}
+
+	objs := make([]*codec.Translation, 0, len(translations))
 
 	for _, item := range translations {
-		objs = append(objs, &translationObject{
-			Lang:  item.Lang.String(),
-			Key:   item.Key,
-			Value: item.Value,
-		})
+		objs = append(objs, codec.EncodeTranslation(item))
 	}
 
 	p := &payload{
 		DefaultLanguage:    defaultLang.String(),
 		SupportedLanguages: s,
 		Translations:       objs,
+		Fallbacks:          f,
+		FallbackToDefault:  toDefault,
 	}
 
 	data, _ := json.Marshal(p)
 	return data
 }
 
-func decode(data []byte) ([]*i18n.Translation, []language.Tag, language.Tag, error) {
+func decode(data []byte) ([]*i18n.Translation, []language.Tag, language.Tag, []*i18n.Fallback, bool, error) {
 	var p payload
 	err := json.Unmarshal(data, &p)
 
 	if err != nil {
-		return nil, nil, language.Und, err
+		return nil, nil, language.Und, nil, false, err
 	}
 
 	s := make([]language.Tag, 0, len(p.SupportedLanguages))
@@ -65,12 +70,24 @@ func decode(data []byte) ([]*i18n.Translation, []language.Tag, language.Tag, err
 
 	t := make([]*i18n.Translation, 0, len(p.Translations))
 	for _, i := range p.Translations {
-		t = append(t, &i18n.Translation{
-			Lang:  language.Make(i.Lang),
-			Key:   i.Key,
-			Value: i.Value,
-		})
+		t = append(t, codec.DecodeTranslation(i))
 	}
 
-	return t, s, d, nil
+	f := make([]*i18n.Fallback, 0, len(p.Fallbacks))
+	for lang, chain := range p.Fallbacks {
+		fallback := &i18n.Fallback{
+			Lang: language.Make(lang),
+		}
+		for _, tag := range chain {
+			fallback.Chain = append(fallback.Chain, language.Make(tag))
+		}
+		f = append(f, fallback)
+	}
+
+	return t, s, d, f, p.FallbackToDefault, nil
+}
+
+func encodeChange(c *i18n.Change) []byte {
+	data, _ := json.Marshal(codec.EncodeChange(c))
+	return data
 }
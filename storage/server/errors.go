@@ -0,0 +1,64 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/net/context"
+
+	"github.com/ThatsMrTalbot/i18n"
+)
+
+// wrap records the operation and backend on server errors
+func wrap(op string, err error) error {
+	return &i18n.StorageError{
+		Backend: "server",
+		Op:      op,
+		Err:     err,
+	}
+}
+
+func readOnly(op string) error {
+	return wrap(op, i18n.ErrReadOnly)
+}
+
+// statusClientClosedRequest is sent when the client goes away before the
+// response is written, as nginx does
+const statusClientClosedRequest = 499
+
+// statusError converts a http status code returned by the server to an error
+func statusError(code int) error {
+	switch code {
+	case http.StatusNotFound:
+		return i18n.ErrNotFound
+	case http.StatusBadRequest:
+		return i18n.ErrInvalidLanguage
+	case http.StatusMethodNotAllowed:
+		return i18n.ErrReadOnly
+	case http.StatusNotImplemented:
+		return i18n.ErrUnsupported
+	}
+
+	return fmt.Errorf("unexpected status %d", code)
+}
+
+// statusCode converts an error to the http status code sent by the server
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, i18n.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, i18n.ErrInvalidLanguage):
+		return http.StatusBadRequest
+	case errors.Is(err, i18n.ErrReadOnly):
+		return http.StatusMethodNotAllowed
+	case errors.Is(err, i18n.ErrUnsupported):
+		return http.StatusNotImplemented
+	case errors.Is(err, context.Canceled):
+		return statusClientClosedRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	}
+
+	return http.StatusInternalServerError
+}
@@ -1,7 +1,12 @@
 package server
 
 import (
+	"errors"
 	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
 
 	"github.com/ThatsMrTalbot/i18n"
 )
@@ -12,6 +17,8 @@ type ResponseMiddleware func(w http.ResponseWriter, r *http.Request) bool
 type Server struct {
 	storage    i18n.Storage
 	middleware []ResponseMiddleware
+	observer   i18n.Observer
+	logger     i18n.Logger
 }
 
 func NewServer(storage i18n.Storage, middleware ...ResponseMiddleware) *Server {
@@ -21,6 +28,22 @@ func NewServer(storage i18n.Storage, middleware ...ResponseMiddleware) *Server {
 	}
 }
 
+// SetObserver sets the observer notified of every request served, it must be
+// set before the server is used
+func (server *Server) SetObserver(observer i18n.Observer) {
+	server.observer = observer
+}
+
+// SetLogger sets the logger for requests that fail and watch streams, it must
+// be set before the server is used
+func (server *Server) SetLogger(logger i18n.Logger) {
+	server.logger = logger
+}
+
+func (server *Server) log() i18n.Logger {
+	return logger(server.logger)
+}
+
 func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	for _, middleware := range server.middleware {
 		if !middleware(w, r) {
@@ -28,24 +51,147 @@ func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	translations, err := server.storage.GetAll()
+	if r.URL.Query().Get("watch") != "" {
+		server.watch(w, r)
+		return
+	}
+
+	// Reads are abandoned if the client goes away
+	ctx := r.Context()
+	start := time.Now()
+
+	get := i18n.WithContext(server.storage).GetAllContext
+
+	// A single language is served using GetLanguage on the storage
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		tag, err := i18n.ParseLanguage(lang)
+		if err != nil {
+			http.Error(w, err.Error(), statusCode(err))
+			return
+		}
+
+		get = func(ctx context.Context) ([]*i18n.Translation, error) {
+			return i18n.GetLanguageContext(ctx, server.storage, tag)
+		}
+	}
+
+	// Review states are served using GetAllInState on the storage, or by
+	// filtering the language if one was asked for
+	if param := r.URL.Query().Get("state"); param != "" {
+		var states []i18n.State
+		for _, s := range strings.Split(param, ",") {
+			state, err := i18n.ParseState(s)
+			if err != nil {
+				http.Error(w, "invalid state parameter", http.StatusBadRequest)
+				return
+			}
+			states = append(states, state)
+		}
+
+		if r.URL.Query().Get("lang") != "" {
+			lang := get
+			get = func(ctx context.Context) ([]*i18n.Translation, error) {
+				translations, err := lang(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return i18n.InState(translations, states...), nil
+			}
+		} else {
+			get = func(ctx context.Context) (translations []*i18n.Translation, err error) {
+				err = i18n.Await(ctx, func() (err error) {
+					translations, err = i18n.GetAllInState(server.storage, states...)
+					return err
+				})
+				return translations, err
+			}
+		}
+	}
+
+	body, err := server.read(ctx, get)
+	observe(server.observer, ctx, "Serve", start, len(body), err)
 
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		server.log().Error("serving translations failed", "status", statusCode(err), "error", err)
+		http.Error(w, err.Error(), statusCode(err))
 		return
 	}
 
-	supported, err := server.storage.SupportedLanguages()
+	w.Write(body)
+}
+
+// read encodes the settings of the storage and the translations got by get
+func (server *Server) read(ctx context.Context, get func(context.Context) ([]*i18n.Translation, error)) ([]byte, error) {
+	storage := i18n.WithContext(server.storage)
+
+	translations, err := get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	supported, err := storage.SupportedLanguagesContext(ctx)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		return nil, err
+	}
+
+	// A storage without a default language is served as undefined
+	def, err := storage.DefaultLanguageContext(ctx)
+	if err != nil && !errors.Is(err, i18n.ErrNotFound) {
+		return nil, err
+	}
+
+	// Fallback settings are only served if the storage keeps them
+	var fallbacks []*i18n.Fallback
+	var toDefault bool
+
+	if fs, ok := i18n.WithFallbackContext(server.storage); ok {
+		fallbacks, err = fs.FallbacksContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		toDefault, err = fs.FallbackToDefaultContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return encode(translations, supported, def, fallbacks, toDefault), nil
+}
+
+// watch streams changes to the storage as newline delimited JSON until the
+// client disconnects
+func (server *Server) watch(w http.ResponseWriter, r *http.Request) {
+	watchable, ok := server.storage.(i18n.WatchableStorage)
+	if !ok {
+		server.log().Warn("watch requested for a storage that can not be watched")
+		http.Error(w, "storage can not be watched", http.StatusNotImplemented)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusNotImplemented)
 		return
 	}
 
-	def, err := server.storage.DefaultLanguage()
+	changes, err := watchable.Watch(r.Context().Done())
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		server.log().Error("watching storage failed", "error", err)
+		http.Error(w, err.Error(), statusCode(err))
 		return
 	}
 
-	w.Write(encode(translations, supported, def))
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	server.log().Debug("watch started", "remote", r.RemoteAddr)
+
+	for change := range changes {
+		w.Write(append(encodeChange(change), '\n'))
+		flusher.Flush()
+	}
+
+	server.log().Debug("watch ended", "remote", r.RemoteAddr)
 }
@@ -1,10 +1,17 @@
 package server
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"golang.org/x/net/context"
 	"golang.org/x/text/language"
 
 	"github.com/ThatsMrTalbot/i18n"
@@ -15,7 +22,7 @@ func TestServerStorage(t *testing.T) {
 	t.Parallel()
 
 	Convey("Given an empty memory store", t, func() {
-		mem := i18n.NewInMemoryStorage()
+		mem := i18n.NewInMemoryStorage().(i18n.FallbackStorage)
 		server := NewServer(mem)
 		host := httptest.NewServer(server)
 
@@ -46,6 +53,31 @@ func TestServerStorage(t *testing.T) {
 			})
 		})
 
+		Convey("When the backing fallback chain is set", func() {
+			mem.StoreFallback(&i18n.Fallback{
+				Lang:  language.BrazilianPortuguese,
+				Chain: []language.Tag{language.Portuguese, language.Spanish},
+			})
+
+			Convey("Then the fallback chain should be correct", func() {
+				fallbacks, err := storage.Fallbacks()
+				So(err, ShouldBeNil)
+				So(fallbacks, ShouldHaveLength, 1)
+				So(fallbacks[0].Lang.String(), ShouldEqual, language.BrazilianPortuguese.String())
+				So(fallbacks[0].Chain, ShouldResemble, []language.Tag{language.Portuguese, language.Spanish})
+			})
+		})
+
+		Convey("When the backing storage falls back to the default language", func() {
+			mem.SetFallbackToDefault(true)
+
+			Convey("Then the setting should be served", func() {
+				enabled, err := storage.FallbackToDefault()
+				So(err, ShouldBeNil)
+				So(enabled, ShouldBeTrue)
+			})
+		})
+
 		Convey("When an item is added to the backing memory store", func() {
 
 			expected := &i18n.Translation{
@@ -65,11 +97,305 @@ func TestServerStorage(t *testing.T) {
 			})
 		})
 
+		Convey("When items in several languages are added to the backing memory store", func() {
+			mem.Store(&i18n.Translation{Lang: language.English, Key: "SomeKey", Value: "SomeValue"})
+			mem.Store(&i18n.Translation{Lang: language.French, Key: "SomeKey", Value: "UneValeur"})
+
+			Convey("Then a single language should be fetched", func() {
+				results, err := storage.GetLanguage(language.French)
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Value, ShouldEqual, "UneValeur")
+			})
+
+			Convey("Then a single language should be filtered once fetched", func() {
+				_, err := storage.GetAll()
+				So(err, ShouldBeNil)
+
+				results, err := storage.GetLanguage(language.English)
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Value, ShouldEqual, "SomeValue")
+			})
+
+			Convey("Then an invalid language should be rejected", func() {
+				resp, err := http.Get(host.URL + "?lang=" + strings.Repeat("x", 20))
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+				So(resp.StatusCode, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+
+		Convey("When a revision in review is added to the backing memory store", func() {
+			mem.Store(&i18n.Translation{Lang: language.English, Key: "OtherKey", Value: "OtherValue"})
+			mem.Store(&i18n.Translation{
+				Lang:     language.English,
+				Key:      "SomeKey",
+				Value:    "ARevisedValue",
+				State:    i18n.StateNeedsReview,
+				Approved: &i18n.Translation{Lang: language.English, Key: "SomeKey", Value: "SomeValue"},
+			})
+
+			Convey("Then only the state should be fetched", func() {
+				results, err := storage.GetAllInState(i18n.StateNeedsReview)
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Value, ShouldEqual, "ARevisedValue")
+				So(results[0].Approved.Value, ShouldEqual, "SomeValue")
+			})
+
+			Convey("Then the state should be filtered once fetched", func() {
+				_, err := storage.GetAll()
+				So(err, ShouldBeNil)
+
+				results, err := storage.GetAllInState(i18n.StateApproved)
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Key, ShouldEqual, "OtherKey")
+			})
+
+			Convey("Then an invalid state should be rejected", func() {
+				resp, err := http.Get(host.URL + "?state=published")
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+				So(resp.StatusCode, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+
+		Convey("When an item with plural variants is added to the backing memory store", func() {
+
+			expected := &i18n.Translation{
+				Lang:  language.English,
+				Key:   "SomeKey",
+				Value: "SomeValue",
+				Plurals: map[i18n.PluralForm]string{
+					i18n.PluralOne:   "SomeValue",
+					i18n.PluralOther: "SomeValues",
+				},
+			}
+
+			err := mem.Store(expected)
+			So(err, ShouldBeNil)
+
+			Convey("Then the variants should be accessable", func() {
+				results, err := storage.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0], ShouldResemble, expected)
+			})
+		})
+
+		Convey("When an item with a state and metadata is added to the backing memory store", func() {
+
+			expected := &i18n.Translation{
+				Lang:  language.English,
+				Key:   "SomeKey",
+				Value: "SomeValue",
+				State: i18n.StateApproved,
+				Metadata: &i18n.Metadata{
+					Description: "Shown on the checkout button",
+					Context:     "verb",
+					MaxLength:   20,
+					Labels:      []string{"web", "checkout"},
+					Created:     time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC),
+					Updated:     time.Date(2016, 2, 3, 4, 5, 6, 0, time.UTC),
+					Author:      "alice",
+					ReviewedBy:  "bob",
+					Reviewed:    time.Date(2016, 2, 4, 5, 6, 7, 0, time.UTC),
+				},
+			}
+
+			err := mem.Store(expected)
+			So(err, ShouldBeNil)
+
+			Convey("Then the state and metadata should be accessable", func() {
+				results, err := storage.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0], ShouldResemble, expected)
+			})
+		})
+
+		Convey("When an item is written to the storage", func() {
+			err := storage.Store(&i18n.Translation{
+				Lang:  language.English,
+				Key:   "SomeKey",
+				Value: "SomeValue",
+			})
+
+			Convey("Then a read only storage error should be returned", func() {
+				var storageErr *i18n.StorageError
+				So(errors.Is(err, i18n.ErrReadOnly), ShouldBeTrue)
+				So(errors.As(err, &storageErr), ShouldBeTrue)
+				So(storageErr.Backend, ShouldEqual, "server")
+				So(storageErr.Op, ShouldEqual, "Store")
+			})
+
+			Convey("Then the storage should be read only", func() {
+				So(i18n.CapabilitiesOf(storage).ReadOnly(), ShouldBeTrue)
+			})
+		})
+
 		Reset(func() {
 			host.Close()
 		})
 	})
 
+	Convey("Given a watched storage", t, func() {
+		mem := i18n.NewInMemoryStorage()
+		server := NewServer(mem)
+		host := httptest.NewServer(server)
+
+		storage := NewStorage(host.URL)
+
+		stop := make(chan struct{})
+		changes, err := storage.Watch(stop)
+		So(err, ShouldBeNil)
+
+		Convey("When an item is added to the backing memory store", func() {
+			expected := &i18n.Translation{
+				Lang:  language.English,
+				Key:   "SomeKey",
+				Value: "SomeValue",
+			}
+
+			mem.Store(expected)
+
+			Convey("Then the change should be received", func() {
+				var change *i18n.Change
+				select {
+				case change = <-changes:
+				case <-time.After(time.Second):
+				}
+
+				So(change, ShouldNotBeNil)
+				So(change.Type, ShouldEqual, i18n.ChangeUpsert)
+				So(change.Translation, ShouldResemble, expected)
+			})
+		})
+
+		Convey("When the stream is disconnected", func() {
+			host.CloseClientConnections()
+
+			Convey("Then it should reconnect and ask for a reload", func() {
+				var change *i18n.Change
+				select {
+				case change = <-changes:
+				case <-time.After(3 * time.Second):
+				}
+
+				So(change, ShouldNotBeNil)
+				So(change.Type, ShouldEqual, i18n.ChangeReload)
+
+				mem.Store(&i18n.Translation{Lang: language.English, Key: "SomeKey", Value: "SomeValue"})
+
+				select {
+				case change = <-changes:
+				case <-time.After(time.Second):
+				}
+
+				So(change.Type, ShouldEqual, i18n.ChangeUpsert)
+			})
+		})
+
+		Reset(func() {
+			close(stop)
+			host.Close()
+		})
+	})
+
+	Convey("Given an observed server and storage", t, func() {
+		metrics := i18n.NewMetrics()
+
+		server := NewServer(i18n.NewInMemoryStorage())
+		server.SetObserver(metrics)
+		host := httptest.NewServer(server)
+
+		storage := NewStorage(host.URL)
+		storage.SetObserver(metrics)
+
+		Convey("When the storage is read twice", func() {
+			storage.GetAll()
+			storage.GetAll()
+
+			Convey("Then one request should be observed on each side", func() {
+				w := httptest.NewRecorder()
+				metrics.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+				So(w.Body.String(), ShouldContainSubstring, "i18n_storage_operations_total{backend=\"server\",op=\"Serve\"} 1\n")
+				So(w.Body.String(), ShouldContainSubstring, "i18n_storage_operations_total{backend=\"server\",op=\"sync\"} 1\n")
+			})
+		})
+
+		Reset(func() {
+			host.Close()
+		})
+	})
+
+	Convey("Given a server that returns garbage", t, func() {
+		host := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("not json"))
+		}))
+		storage := NewStorage(host.URL)
+
+		out := &bytes.Buffer{}
+		storage.SetLogger(slog.New(slog.NewTextHandler(out, nil)))
+
+		Convey("When the storage is read", func() {
+			_, err := storage.GetAll()
+
+			Convey("Then the decode failure should be logged", func() {
+				So(err, ShouldNotBeNil)
+				So(out.String(), ShouldContainSubstring, `level=ERROR msg="translations could not be decoded"`)
+			})
+		})
+
+		Reset(func() {
+			host.Close()
+		})
+	})
+
+	Convey("Given a server that fails", t, func() {
+		host := httptest.NewServer(http.NotFoundHandler())
+		storage := NewStorage(host.URL)
+
+		Convey("When the storage is read", func() {
+			_, err := storage.GetAll()
+
+			Convey("Then the status should be returned as an error", func() {
+				So(errors.Is(err, i18n.ErrNotFound), ShouldBeTrue)
+			})
+		})
+
+		Reset(func() {
+			host.Close()
+		})
+	})
+
+	Convey("Given a server that hangs", t, func() {
+		release := make(chan struct{})
+		host := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		storage := NewStorage(host.URL)
+
+		Convey("When the storage is read with a deadline", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			_, err := storage.GetAllContext(ctx)
+
+			Convey("Then an error should be returned", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Reset(func() {
+			close(release)
+			host.Close()
+		})
+	})
+
 	Convey("Given a populated storage", t, func() {
 		mem := i18n.NewInMemoryStorage()
 		server := NewServer(mem)
@@ -129,3 +455,25 @@ func ShouldContainLanguage(actual interface{}, expected ...interface{}) string {
 
 	return fmt.Sprintf("Expected collection to contain %s but it did not!", needle.String())
 }
+
+func TestStatusCode(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given errors from the backing storage", t, func() {
+		Convey("Then each should be sent with a matching status", func() {
+			So(statusCode(wrap("GetAll", i18n.ErrNotFound)), ShouldEqual, http.StatusNotFound)
+			So(statusCode(&i18n.LanguageError{Lang: "x", Err: errors.New("bad")}), ShouldEqual, http.StatusBadRequest)
+			So(statusCode(readOnly("Store")), ShouldEqual, http.StatusMethodNotAllowed)
+			So(statusCode(wrap("GetAll", i18n.ErrUnsupported)), ShouldEqual, http.StatusNotImplemented)
+			So(statusCode(context.Canceled), ShouldEqual, statusClientClosedRequest)
+			So(statusCode(wrap("GetAll", context.DeadlineExceeded)), ShouldEqual, http.StatusGatewayTimeout)
+			So(statusCode(errors.New("broken")), ShouldEqual, http.StatusInternalServerError)
+		})
+
+		Convey("Then the client should read each status back as the error", func() {
+			for _, err := range []error{i18n.ErrNotFound, i18n.ErrInvalidLanguage, i18n.ErrReadOnly, i18n.ErrUnsupported} {
+				So(statusError(statusCode(err)), ShouldEqual, err)
+			}
+		})
+	})
+}
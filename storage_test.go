@@ -1,9 +1,12 @@
 package i18n
 
 import (
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
+	"golang.org/x/net/context"
 	"golang.org/x/text/language"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -13,7 +16,7 @@ func TestInMemoryStorage(t *testing.T) {
 	t.Parallel()
 
 	Convey("Given an empty memory store", t, func() {
-		storage := NewInMemoryStorage()
+		storage := NewInMemoryStorage().(FallbackStorage)
 
 		Convey("When the default language is set", func() {
 			storage.SetDefaultLanguage(language.English)
@@ -40,6 +43,30 @@ func TestInMemoryStorage(t *testing.T) {
 			})
 		})
 
+		Convey("When the fallback chain is set", func() {
+			storage.StoreFallback(&Fallback{
+				Lang:  language.BrazilianPortuguese,
+				Chain: []language.Tag{language.Portuguese, language.Spanish},
+			})
+			storage.StoreFallback(&Fallback{
+				Lang:  language.BrazilianPortuguese,
+				Chain: []language.Tag{language.Spanish},
+			})
+			storage.StoreFallback(&Fallback{
+				Lang:  language.CanadianFrench,
+				Chain: []language.Tag{language.French},
+			})
+			storage.DeleteFallback(language.CanadianFrench)
+
+			Convey("Then the fallback chain should be correct", func() {
+				fallbacks, err := storage.Fallbacks()
+				So(err, ShouldBeNil)
+				So(fallbacks, ShouldHaveLength, 1)
+				So(fallbacks[0].Lang.String(), ShouldEqual, language.BrazilianPortuguese.String())
+				So(fallbacks[0].Chain, ShouldResemble, []language.Tag{language.Spanish})
+			})
+		})
+
 		Convey("When an item is added to the memory store", func() {
 
 			expected := &Translation{
@@ -91,6 +118,29 @@ func TestInMemoryStorage(t *testing.T) {
 				So(results[0], ShouldResemble, replacement)
 			})
 		})
+
+		Convey("When an item with plural variants is added to the memory store", func() {
+
+			expected := &Translation{
+				Lang:  language.English,
+				Key:   "SomeKey",
+				Value: "SomeValue",
+				Plurals: map[PluralForm]string{
+					PluralOne:   "SomeValue",
+					PluralOther: "SomeValues",
+				},
+			}
+
+			err := storage.Store(expected)
+			So(err, ShouldBeNil)
+
+			Convey("Then the variants should be accessable", func() {
+				results, err := storage.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0], ShouldResemble, expected)
+			})
+		})
 	})
 
 	Convey("Given a populated storage", t, func() {
@@ -142,3 +192,80 @@ func ShouldContainLanguage(actual interface{}, expected ...interface{}) string {
 
 	return fmt.Sprintf("Expected collection to contain %s but it did not!", needle.String())
 }
+
+// slowStorage blocks reads until it is released
+type slowStorage struct {
+	Storage
+
+	release chan struct{}
+}
+
+func (storage *slowStorage) GetAll() ([]*Translation, error) {
+	<-storage.release
+	return storage.Storage.GetAll()
+}
+
+func TestContextStorage(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a storage that hangs", t, func() {
+		backing := NewInMemoryStorage()
+		backing.Store(&Translation{
+			Lang:  language.English,
+			Key:   "SomeKey",
+			Value: "SomeValue",
+		})
+
+		storage := &slowStorage{
+			Storage: backing,
+			release: make(chan struct{}),
+		}
+
+		i18n := New(storage)
+
+		Convey("When it is read with a deadline", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			_, err := WithContext(storage).GetAllContext(ctx)
+
+			Convey("Then the deadline error should be returned", func() {
+				So(err, ShouldEqual, context.DeadlineExceeded)
+			})
+		})
+
+		Convey("When it is synced with a deadline", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			err := i18n.SyncContext(ctx)
+
+			Convey("Then the catalog should be unchanged", func() {
+				So(err, ShouldEqual, context.DeadlineExceeded)
+				So(i18n.T(language.English, "SomeKey"), ShouldEqual, "")
+			})
+		})
+
+		Convey("When it is written with a cancelled context", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := i18n.AddContext(ctx, &Translation{
+				Lang:  language.English,
+				Key:   "OtherKey",
+				Value: "OtherValue",
+			})
+
+			Convey("Then the write should not be made", func() {
+				So(errors.Is(err, context.Canceled), ShouldBeTrue)
+
+				results, _ := backing.GetAll()
+				So(results, ShouldHaveLength, 1)
+			})
+		})
+
+		Reset(func() {
+			close(storage.release)
+		})
+	})
+}
@@ -0,0 +1,195 @@
+package i18n
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Syncer keeps an I18n in sync with its storage in the background. A failed
+// sync is retried with exponential backoff and jitter, starting at the minimum
+// backoff and doubling up to the maximum. A sync that skips translations that
+// can not be parsed still replaces the catalog, so it counts as successful and
+// the SyntaxError is only passed to the error handler and LastError.
+type Syncer struct {
+	i18n     *I18n
+	interval time.Duration
+
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	onError    func(error)
+
+	lock      sync.Mutex
+	lastSync  time.Time
+	lastError error
+
+	ready     chan struct{}
+	readyOnce sync.Once
+}
+
+// MinSyncInterval is the shortest interval a Syncer syncs at, shorter
+// intervals, including zero and negative ones, are raised to it
+const MinSyncInterval = time.Second
+
+// NewSyncer creates a syncer that syncs every interval, by default failures
+// are retried after one second backing off up to the interval. Intervals
+// shorter than MinSyncInterval are raised to it.
+func NewSyncer(i18n *I18n, interval time.Duration) *Syncer {
+	if interval < MinSyncInterval {
+		interval = MinSyncInterval
+	}
+
+	return &Syncer{
+		i18n:       i18n,
+		interval:   interval,
+		minBackoff: time.Second,
+		maxBackoff: interval,
+		ready:      make(chan struct{}),
+	}
+}
+
+// SetBackoff sets how long to wait before retrying a failed sync, the wait
+// doubles after each failure from min up to max
+func (syncer *Syncer) SetBackoff(min time.Duration, max time.Duration) {
+	syncer.lock.Lock()
+	defer syncer.lock.Unlock()
+
+	syncer.minBackoff = min
+	syncer.maxBackoff = max
+}
+
+// SetErrorHandler sets a function called with every sync error
+func (syncer *Syncer) SetErrorHandler(f func(error)) {
+	syncer.lock.Lock()
+	defer syncer.lock.Unlock()
+
+	syncer.onError = f
+}
+
+// Ready gets a channel that is closed after the first successful sync
+func (syncer *Syncer) Ready() <-chan struct{} {
+	return syncer.ready
+}
+
+// LastSync gets the time of the last successful sync
+func (syncer *Syncer) LastSync() time.Time {
+	syncer.lock.Lock()
+	defer syncer.lock.Unlock()
+
+	return syncer.lastSync
+}
+
+// LastError gets the error from the last sync, or nil if it succeeded without
+// skipping translations
+func (syncer *Syncer) LastError() error {
+	syncer.lock.Lock()
+	defer syncer.lock.Unlock()
+
+	return syncer.lastError
+}
+
+// Run syncs immediately and then every interval until the context is done,
+// returning the context error
+func (syncer *Syncer) Run(ctx context.Context) error {
+	failures := 0
+
+	for {
+		if syncer.sync(ctx) {
+			failures = 0
+		} else {
+			failures++
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		wait := syncer.wait(failures)
+		if failures > 0 {
+			syncer.i18n.logger().Warn("sync will be retried", "failures", failures, "retry_in", wait)
+		}
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// sync runs a single sync and records the outcome, a sync cut short by the
+// context being done is not recorded
+func (syncer *Syncer) sync(ctx context.Context) bool {
+	err := syncer.i18n.SyncContext(ctx)
+	if err != nil && ctx.Err() != nil {
+		return false
+	}
+
+	var syntaxErr *SyntaxError
+	published := err == nil || errors.As(err, &syntaxErr)
+
+	syncer.lock.Lock()
+	syncer.lastError = err
+	if published {
+		syncer.lastSync = time.Now()
+	}
+	onError := syncer.onError
+	syncer.lock.Unlock()
+
+	if err != nil && onError != nil {
+		onError(err)
+	}
+
+	if !published {
+		return false
+	}
+
+	syncer.readyOnce.Do(func() {
+		close(syncer.ready)
+	})
+
+	return true
+}
+
+// wait gets how long to wait before the next sync. After a failure the backoff
+// is halved and the other half jittered, otherwise up to a tenth of the
+// interval is jittered.
+func (syncer *Syncer) wait(failures int) time.Duration {
+	syncer.lock.Lock()
+	defer syncer.lock.Unlock()
+
+	if failures == 0 {
+		return syncer.interval - jitter(syncer.interval/10)
+	}
+
+	return Backoff(failures, syncer.minBackoff, syncer.maxBackoff)
+}
+
+// Backoff gets how long to wait after a number of consecutive failures,
+// starting at min and doubling up to max. Half of the wait is jittered so
+// clients that failed together do not retry together.
+func Backoff(failures int, min time.Duration, max time.Duration) time.Duration {
+	backoff := min
+	for i := 1; i < failures && backoff < max; i++ {
+		backoff *= 2
+	}
+
+	if backoff > max {
+		backoff = max
+	}
+
+	return backoff/2 + jitter(backoff/2)
+}
+
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
@@ -0,0 +1,184 @@
+package i18n
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/text/language"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// flakyStorage fails GetAll a number of times before succeeding
+type flakyStorage struct {
+	Storage
+
+	lock     sync.Mutex
+	failures int
+	calls    []time.Time
+}
+
+func (storage *flakyStorage) GetAll() ([]*Translation, error) {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
+	storage.calls = append(storage.calls, time.Now())
+	if storage.failures > 0 {
+		storage.failures--
+		return nil, errFailing
+	}
+
+	return storage.Storage.GetAll()
+}
+
+func TestSyncer(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a syncer for a storage that fails twice", t, func() {
+		storage := &flakyStorage{
+			Storage:  NewInMemoryStorage(),
+			failures: 2,
+		}
+		storage.Store(&Translation{
+			Lang:  language.English,
+			Key:   "SomeKey",
+			Value: "SomeValue",
+		})
+
+		i18n := New(storage)
+		syncer := NewSyncer(i18n, time.Hour)
+		syncer.SetBackoff(10*time.Millisecond, 40*time.Millisecond)
+
+		var lock sync.Mutex
+		var errs []error
+		syncer.SetErrorHandler(func(err error) {
+			lock.Lock()
+			defer lock.Unlock()
+			errs = append(errs, err)
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+
+		Convey("When it is run", func() {
+			So(syncer.LastSync().IsZero(), ShouldBeTrue)
+
+			go func() {
+				done <- syncer.Run(ctx)
+			}()
+
+			Convey("Then it should become ready once a sync succeeds", func() {
+				select {
+				case <-syncer.Ready():
+				case <-time.After(time.Second):
+				}
+
+				So(i18n.T(language.English, "SomeKey"), ShouldEqual, "SomeValue")
+				So(syncer.LastError(), ShouldBeNil)
+				So(syncer.LastSync().IsZero(), ShouldBeFalse)
+			})
+
+			Convey("Then each failure should be reported and retried", func() {
+				<-syncer.Ready()
+
+				lock.Lock()
+				defer lock.Unlock()
+				So(errs, ShouldHaveLength, 2)
+				So(errors.Is(errs[0], errFailing), ShouldBeTrue)
+
+				storage.lock.Lock()
+				defer storage.lock.Unlock()
+				So(storage.calls, ShouldHaveLength, 3)
+				So(storage.calls[1].Sub(storage.calls[0]), ShouldBeGreaterThanOrEqualTo, 5*time.Millisecond)
+				So(storage.calls[2].Sub(storage.calls[1]), ShouldBeGreaterThanOrEqualTo, 10*time.Millisecond)
+			})
+
+			Convey("Then cancelling should stop it", func() {
+				cancel()
+
+				var err error
+				select {
+				case err = <-done:
+				case <-time.After(time.Second):
+				}
+				So(err, ShouldEqual, context.Canceled)
+			})
+		})
+
+		Reset(func() {
+			cancel()
+			i18n.Close()
+		})
+	})
+
+	Convey("Given a syncer for a storage with a translation that can not be parsed", t, func() {
+		storage := NewInMemoryStorage()
+		storage.Store(&Translation{Lang: language.English, Key: "Invalid", Value: "{count, plural, one {# file}", ICU: true})
+		storage.Store(&Translation{Lang: language.English, Key: "Valid", Value: "SomeValue"})
+
+		i18n := New(storage)
+		recorder := new(recordingObserver)
+		i18n.SetObserver(recorder)
+
+		syncer := NewSyncer(i18n, time.Hour)
+
+		var errs []error
+		syncer.SetErrorHandler(func(err error) {
+			errs = append(errs, err)
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go syncer.Run(ctx)
+
+		Convey("When it is run", func() {
+			select {
+			case <-syncer.Ready():
+			case <-time.After(time.Second):
+			}
+
+			Convey("Then the sync should count as successful", func() {
+				So(i18n.T(language.English, "Valid"), ShouldEqual, "SomeValue")
+				So(syncer.LastSync().IsZero(), ShouldBeFalse)
+				So(syncer.LastError(), ShouldHaveSameTypeAs, &SyntaxError{})
+				So(errs, ShouldHaveLength, 1)
+			})
+
+			Convey("Then the skipped translation should be observed", func() {
+				recorder.lock.Lock()
+				defer recorder.lock.Unlock()
+
+				So(recorder.syncs, ShouldHaveLength, 1)
+				So(recorder.syncs[0].Err, ShouldBeNil)
+				So(recorder.syncs[0].Skipped, ShouldEqual, 1)
+				So(recorder.syncs[0].Translations, ShouldEqual, 1)
+			})
+		})
+
+		Reset(func() {
+			cancel()
+			i18n.Close()
+		})
+	})
+	Convey("Given a syncer with an interval of zero", t, func() {
+		storage := &flakyStorage{Storage: NewInMemoryStorage()}
+		syncer := NewSyncer(New(storage), 0)
+
+		Convey("When it is run", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			err := syncer.Run(ctx)
+
+			Convey("Then it should sync once rather than busy loop", func() {
+				So(err, ShouldEqual, context.DeadlineExceeded)
+
+				storage.lock.Lock()
+				defer storage.lock.Unlock()
+				So(storage.calls, ShouldHaveLength, 1)
+			})
+		})
+	})
+}
@@ -0,0 +1,492 @@
+package i18n
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/text/language"
+)
+
+// ChangeType describes what changed in a storage
+type ChangeType int
+
+const (
+	// ChangeUpsert is a translation being added or updated
+	ChangeUpsert ChangeType = iota
+
+	// ChangeDelete is a translation being deleted
+	ChangeDelete
+
+	// ChangeLanguages is the supported languages, default language or
+	// fallback chains being changed
+	ChangeLanguages
+
+	// ChangeReload is any number of translations being changed, everything
+	// should be reloaded
+	ChangeReload
+)
+
+var changeTypes = map[ChangeType]string{
+	ChangeUpsert:    "upsert",
+	ChangeDelete:    "delete",
+	ChangeLanguages: "languages",
+	ChangeReload:    "reload",
+}
+
+func (t ChangeType) String() string {
+	if s, ok := changeTypes[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("ChangeType(%d)", int(t))
+}
+
+// MarshalText encodes the change type as its name
+func (t ChangeType) MarshalText() ([]byte, error) {
+	if s, ok := changeTypes[t]; ok {
+		return []byte(s), nil
+	}
+	return nil, fmt.Errorf("i18n: unknown change type %d", int(t))
+}
+
+// UnmarshalText decodes a change type from its name
+func (t *ChangeType) UnmarshalText(text []byte) error {
+	for changeType, s := range changeTypes {
+		if s == string(text) {
+			*t = changeType
+			return nil
+		}
+	}
+	return fmt.Errorf("i18n: unknown change type %q", text)
+}
+
+// Change is emitted when a storage is modified, Translation is set for
+// upserts and deletes
+type Change struct {
+	Type        ChangeType
+	Translation *Translation
+}
+
+// WatchableStorage is implemented by storages that push changes as they
+// happen, rather than relying on a refresh interval
+type WatchableStorage interface {
+	Storage
+
+	// Watch sends changes to the returned channel until stop is closed, the
+	// channel is closed once watching stops
+	Watch(stop <-chan struct{}) (<-chan *Change, error)
+}
+
+// Watch applies changes pushed by every WatchableStorage to the catalog as
+// they happen. Close stops watching.
+func (i18n *I18n) Watch() error {
+	i18n.lock.Lock()
+	defer i18n.lock.Unlock()
+
+	if i18n.watching != nil {
+		return nil
+	}
+
+	stop := make(chan struct{})
+	var feeds []<-chan *Change
+
+	var sources []int
+
+	for i, s := range i18n.storage {
+		if watchable, ok := unwrap(s).(WatchableStorage); ok {
+			changes, err := watchable.Watch(stop)
+			if err != nil {
+				close(stop)
+				return err
+			}
+			feeds = append(feeds, changes)
+			sources = append(sources, i)
+		}
+	}
+
+	i18n.feeds = len(feeds)
+
+	for i, changes := range feeds {
+		go func(source int, changes <-chan *Change) {
+			for change := range changes {
+				i18n.apply(source, change)
+			}
+			i18n.logger().Debug("watch stopped")
+		}(sources[i], changes)
+	}
+
+	i18n.watching = stop
+
+	return nil
+}
+
+// apply updates the catalog with a change pushed by the storage at index
+// source, upserts of ICU translations that can not be parsed are ignored.
+// Changes echoing a write made through this I18n are skipped, as the catalog
+// already has them.
+func (i18n *I18n) apply(source int, change *Change) {
+	switch change.Type {
+	case ChangeUpsert, ChangeDelete:
+		if !i18n.applyTranslation(source, change) {
+			return
+		}
+	case ChangeLanguages:
+		i18n.lock.Lock()
+		defer i18n.lock.Unlock()
+
+		next := i18n.load().clone()
+		if err := i18n.loadLanguages(context.Background(), next); err != nil {
+			i18n.logger().Error("loading languages failed", "error", err)
+			return
+		}
+		i18n.catalog.Store(next)
+	case ChangeReload:
+		i18n.Sync()
+		return
+	default:
+		return
+	}
+
+	i18n.notify(change)
+}
+
+// applyTranslation applies an upsert or delete, reporting whether the catalog
+// was updated. Upserts from the storage with the highest priority are applied
+// as they are. Otherwise the key is read again from every storage and resolved
+// the same way as Sync, so a storage with a lower priority never overwrites one
+// with a higher priority. Changes to languages not loaded in lazy mode are left
+// for the language to be loaded.
+func (i18n *I18n) applyTranslation(source int, change *Change) bool {
+	translation := change.Translation
+	logger := i18n.logger()
+
+	var message *Message
+	if change.Type == ChangeUpsert {
+		var err error
+		if message, err = translation.message(); err != nil {
+			logger.Warn("ignoring change that can not be parsed", "lang", translation.Lang.String(), "key", translation.Key, "error", err)
+			return false
+		}
+	}
+
+	i18n.lock.Lock()
+
+	if i18n.echoed(change) {
+		i18n.lock.Unlock()
+		return false
+	}
+
+	c := i18n.load()
+
+	if c.lazy.enabled {
+		if _, ok := c.loaded[translation.Lang.String()]; !ok {
+			i18n.lock.Unlock()
+			return false
+		}
+	}
+
+	switch {
+	case change.Type == ChangeUpsert && source == len(i18n.storage)-1:
+		i18n.catalog.Store(c.withTranslation(translation, message))
+		i18n.lock.Unlock()
+		return true
+	case change.Type == ChangeDelete && len(i18n.storage) == 1:
+		i18n.catalog.Store(c.withoutTranslation(translation))
+		i18n.lock.Unlock()
+		return true
+	}
+
+	i18n.lock.Unlock()
+
+	// The storages are read without the lock, the result is discarded if the
+	// catalog is written to meanwhile
+	for attempt := 1; attempt <= loadAttempts; attempt++ {
+		resolved, message, err := i18n.resolveKey(context.Background(), translation.Lang, translation.Key)
+		if err != nil {
+			logger.Error("resolving change failed", "lang", translation.Lang.String(), "key", translation.Key, "error", err)
+			return false
+		}
+
+		i18n.lock.Lock()
+		if current := i18n.load(); current != c {
+			c = current
+			i18n.lock.Unlock()
+			continue
+		}
+
+		if resolved == nil {
+			i18n.catalog.Store(c.withoutTranslation(translation))
+		} else {
+			i18n.catalog.Store(c.withTranslation(resolved, message))
+		}
+		i18n.lock.Unlock()
+		return true
+	}
+
+	logger.Debug("catalog kept changing while resolving change, reloading", "lang", translation.Lang.String(), "key", translation.Key)
+	i18n.Sync()
+	return false
+}
+
+// resolveKey reads a key from the storage with the highest priority that has
+// it, translations that can not be parsed are skipped as they are by Sync. It
+// returns nil if no storage has the key.
+func (i18n *I18n) resolveKey(ctx context.Context, lang language.Tag, key string) (*Translation, *Message, error) {
+	for i := len(i18n.storage) - 1; i >= 0; i-- {
+		translations, err := GetLanguageContext(ctx, i18n.storage[i], lang)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, translation := range translations {
+			if translation.Key != key {
+				continue
+			}
+			if message, err := translation.message(); err == nil {
+				return translation, message, nil
+			}
+		}
+	}
+	return nil, nil, nil
+}
+
+// OnChange registers a function that is called with every change applied to
+// the catalog, whether it was made through I18n, pushed by a storage or loaded
+// by Sync. The same change may be reported more than once. It is called while
+// the change is applied so it must not modify I18n. The returned function
+// removes it.
+func (i18n *I18n) OnChange(f func(*Change)) func() {
+	i18n.listenersLock.Lock()
+	defer i18n.listenersLock.Unlock()
+
+	if i18n.listeners == nil {
+		i18n.listeners = make(map[int]func(*Change))
+	}
+
+	id := i18n.nextListener
+	i18n.nextListener++
+	i18n.listeners[id] = f
+
+	return func() {
+		i18n.listenersLock.Lock()
+		defer i18n.listenersLock.Unlock()
+
+		delete(i18n.listeners, id)
+	}
+}
+
+func (i18n *I18n) notify(changes ...*Change) {
+	i18n.listenersLock.Lock()
+	listeners := make([]func(*Change), 0, len(i18n.listeners))
+	for _, f := range i18n.listeners {
+		listeners = append(listeners, f)
+	}
+	i18n.listenersLock.Unlock()
+
+	for _, change := range changes {
+		for _, f := range listeners {
+			f(change)
+		}
+	}
+}
+
+// publish notifies listeners of changes written through this I18n and expects
+// each watched storage to echo them back. It must be called with the lock held.
+func (i18n *I18n) publish(changes ...*Change) {
+	i18n.expect(changes)
+	i18n.notify(changes...)
+}
+
+const (
+	// echoTTL is how long a watched storage has to echo a write back
+	echoTTL = time.Minute
+
+	// maxEchoes bounds the writes waiting to be echoed, writes beyond it are
+	// applied again when their echo arrives
+	maxEchoes = 4096
+)
+
+type echo struct {
+	count   int
+	expires time.Time
+}
+
+// echoKey identifies a change, deletes only need the language and key.
+// Upserts are identified by a hash of every field, so a change to any of them
+// made elsewhere is not mistaken for an echo.
+func echoKey(change *Change) (string, bool) {
+	switch change.Type {
+	case ChangeUpsert:
+		encoded, err := json.Marshal(change.Translation)
+		if err != nil {
+			return "", false
+		}
+		hash := sha256.Sum256(encoded)
+		return fmt.Sprintf("u\x00%s\x00%s\x00%s", change.Translation.Lang, change.Translation.Key, hex.EncodeToString(hash[:])), true
+	case ChangeDelete:
+		return fmt.Sprintf("d\x00%s\x00%s", change.Translation.Lang, change.Translation.Key), true
+	}
+	return "", false
+}
+
+// expect records changes as waiting to be echoed once by every watched storage
+func (i18n *I18n) expect(changes []*Change) {
+	if i18n.feeds == 0 {
+		return
+	}
+
+	now := time.Now()
+
+	if i18n.echoes == nil {
+		i18n.echoes = make(map[string]*echo)
+	}
+
+	if len(i18n.echoes)+len(changes) > maxEchoes {
+		for key, e := range i18n.echoes {
+			if now.After(e.expires) {
+				delete(i18n.echoes, key)
+			}
+		}
+	}
+
+	for _, change := range changes {
+		key, ok := echoKey(change)
+		if !ok {
+			continue
+		}
+
+		if e, ok := i18n.echoes[key]; ok {
+			e.count += i18n.feeds
+			e.expires = now.Add(echoTTL)
+			continue
+		}
+
+		if len(i18n.echoes) >= maxEchoes {
+			return
+		}
+
+		i18n.echoes[key] = &echo{
+			count:   i18n.feeds,
+			expires: now.Add(echoTTL),
+		}
+	}
+}
+
+// echoed reports whether a change pushed by a storage echoes a write made
+// through this I18n. It must be called with the lock held.
+func (i18n *I18n) echoed(change *Change) bool {
+	key, ok := echoKey(change)
+	if !ok {
+		return false
+	}
+
+	e, ok := i18n.echoes[key]
+	if !ok {
+		return false
+	}
+
+	if time.Now().After(e.expires) {
+		delete(i18n.echoes, key)
+		return false
+	}
+
+	e.count--
+	if e.count == 0 {
+		delete(i18n.echoes, key)
+	}
+
+	return true
+}
+
+// ChangesFor makes a change of the same type for each translation, for
+// storages publishing a batch of writes
+func ChangesFor(changeType ChangeType, translations []*Translation) []*Change {
+	result := make([]*Change, 0, len(translations))
+	for _, translation := range translations {
+		result = append(result, &Change{
+			Type:        changeType,
+			Translation: translation,
+		})
+	}
+	return result
+}
+
+// changeFeed fans changes out to watchers without ever blocking the storage
+// emitting them, changes are queued until each watcher receives them
+type changeFeed struct {
+	lock     sync.Mutex
+	watchers map[*changeWatcher]bool
+}
+
+type changeWatcher struct {
+	lock   sync.Mutex
+	queue  []*Change
+	signal chan struct{}
+}
+
+func (feed *changeFeed) watch(stop <-chan struct{}) <-chan *Change {
+	watcher := &changeWatcher{
+		signal: make(chan struct{}, 1),
+	}
+
+	feed.lock.Lock()
+	if feed.watchers == nil {
+		feed.watchers = make(map[*changeWatcher]bool)
+	}
+	feed.watchers[watcher] = true
+	feed.lock.Unlock()
+
+	changes := make(chan *Change)
+
+	go func() {
+		defer close(changes)
+		defer func() {
+			feed.lock.Lock()
+			delete(feed.watchers, watcher)
+			feed.lock.Unlock()
+		}()
+
+		for {
+			watcher.lock.Lock()
+			queue := watcher.queue
+			watcher.queue = nil
+			watcher.lock.Unlock()
+
+			for _, change := range queue {
+				select {
+				case changes <- change:
+				case <-stop:
+					return
+				}
+			}
+
+			select {
+			case <-watcher.signal:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return changes
+}
+
+func (feed *changeFeed) publish(changes ...*Change) {
+	feed.lock.Lock()
+	defer feed.lock.Unlock()
+
+	for watcher := range feed.watchers {
+		watcher.lock.Lock()
+		watcher.queue = append(watcher.queue, changes...)
+		watcher.lock.Unlock()
+
+		select {
+		case watcher.signal <- struct{}{}:
+		default:
+		}
+	}
+}
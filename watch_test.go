@@ -0,0 +1,214 @@
+package i18n
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// eventually polls f until it returns true or a second has passed
+func eventually(f func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if f() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return f()
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given two instances sharing a watchable storage", t, func() {
+		storage := NewInMemoryStorage()
+		writer := New(storage)
+		reader := New(storage)
+
+		err := reader.Watch()
+		So(err, ShouldBeNil)
+
+		var changes []*Change
+		received := make(chan *Change, 16)
+		remove := reader.OnChange(func(change *Change) {
+			received <- change
+		})
+
+		Convey("When a translation is added through the other instance", func() {
+			err := writer.Add(&Translation{
+				Lang:  language.English,
+				Key:   "SomeKey",
+				Value: "SomeValue",
+			})
+			So(err, ShouldBeNil)
+
+			Convey("Then it should be applied without a sync", func() {
+				So(eventually(func() bool {
+					return reader.T(language.English, "SomeKey") == "SomeValue"
+				}), ShouldBeTrue)
+			})
+
+			Convey("Then the change should be reported", func() {
+				select {
+				case change := <-received:
+					changes = append(changes, change)
+				case <-time.After(time.Second):
+				}
+
+				So(changes, ShouldHaveLength, 1)
+				So(changes[0].Type, ShouldEqual, ChangeUpsert)
+				So(changes[0].Translation.Key, ShouldEqual, "SomeKey")
+			})
+
+			Convey("Then a delete should be applied", func() {
+				So(eventually(func() bool {
+					return reader.Get(language.English, "SomeKey") != nil
+				}), ShouldBeTrue)
+
+				err := writer.Delete(&Translation{
+					Lang: language.English,
+					Key:  "SomeKey",
+				})
+				So(err, ShouldBeNil)
+
+				So(eventually(func() bool {
+					return reader.Get(language.English, "SomeKey") == nil
+				}), ShouldBeTrue)
+			})
+		})
+
+		Convey("When the language config is changed through the other instance", func() {
+			err := writer.SetFallback(language.CanadianFrench, language.English)
+			So(err, ShouldBeNil)
+
+			Convey("Then it should be applied without a sync", func() {
+				So(eventually(func() bool {
+					return len(reader.GetFallback(language.CanadianFrench)) == 1
+				}), ShouldBeTrue)
+			})
+		})
+
+		Convey("When translations are written through the watching instance", func() {
+			reader.Add(&Translation{Lang: language.English, Key: "SomeKey", Value: "SomeValue"})
+			reader.Add(&Translation{Lang: language.English, Key: "SomeKey", Value: "OtherValue"})
+
+			// Changes are pushed in order, so the echoes have been handled once
+			// a later write by the other instance is applied
+			writer.Add(&Translation{Lang: language.English, Key: "OtherKey", Value: "OtherValue"})
+			So(eventually(func() bool {
+				return reader.T(language.English, "OtherKey") == "OtherValue"
+			}), ShouldBeTrue)
+
+			Convey("Then the echoed writes should not be applied again", func() {
+				So(received, ShouldHaveLength, 3)
+				So(reader.T(language.English, "SomeKey"), ShouldEqual, "OtherValue")
+			})
+		})
+
+		Convey("When the listener is removed", func() {
+			remove()
+			reader.Add(&Translation{
+				Lang:  language.English,
+				Key:   "SomeKey",
+				Value: "SomeValue",
+			})
+
+			Convey("Then changes should no longer be reported", func() {
+				So(received, ShouldBeEmpty)
+			})
+		})
+
+		Reset(func() {
+			writer.Close()
+			reader.Close()
+		})
+	})
+}
+
+func TestWatchPrecedence(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given an instance watching two storages", t, func() {
+		low := NewInMemoryStorage()
+		high := NewInMemoryStorage()
+
+		New(high).Add(&Translation{Lang: language.English, Key: "SomeKey", Value: "HighValue"})
+
+		reader := New(low, high)
+		So(reader.Sync(), ShouldBeNil)
+		So(reader.Watch(), ShouldBeNil)
+
+		Convey("When the storage with the lower priority changes the key", func() {
+			writer := New(low)
+			writer.Add(&Translation{Lang: language.English, Key: "SomeKey", Value: "LowValue"})
+
+			// Changes are pushed in order, so the first change has been handled
+			// once a later one is applied
+			writer.Add(&Translation{Lang: language.English, Key: "OtherKey", Value: "OtherValue"})
+			So(eventually(func() bool {
+				return reader.T(language.English, "OtherKey") == "OtherValue"
+			}), ShouldBeTrue)
+
+			Convey("Then the storage with the higher priority should win", func() {
+				So(reader.T(language.English, "SomeKey"), ShouldEqual, "HighValue")
+			})
+
+			Convey("Then deleting it from the higher priority storage should reveal the lower one", func() {
+				New(high).Delete(&Translation{Lang: language.English, Key: "SomeKey"})
+
+				So(eventually(func() bool {
+					return reader.T(language.English, "SomeKey") == "LowValue"
+				}), ShouldBeTrue)
+			})
+		})
+
+		Reset(func() {
+			reader.Close()
+		})
+	})
+}
+
+func TestEchoKey(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given two upserts differing only in state", t, func() {
+		draft := &Change{Type: ChangeUpsert, Translation: &Translation{Lang: language.English, Key: "SomeKey", Value: "SomeValue", State: StateDraft}}
+		approved := &Change{Type: ChangeUpsert, Translation: &Translation{Lang: language.English, Key: "SomeKey", Value: "SomeValue", State: StateApproved}}
+
+		Convey("Then they should not be mistaken for each other", func() {
+			draftKey, ok := echoKey(draft)
+			So(ok, ShouldBeTrue)
+
+			approvedKey, ok := echoKey(approved)
+			So(ok, ShouldBeTrue)
+
+			So(draftKey, ShouldNotEqual, approvedKey)
+		})
+	})
+}
+
+func TestChangeType(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a change type", t, func() {
+		Convey("Then it should round trip through its name", func() {
+			text, err := ChangeLanguages.MarshalText()
+			So(err, ShouldBeNil)
+			So(string(text), ShouldEqual, "languages")
+
+			var changeType ChangeType
+			err = changeType.UnmarshalText(text)
+			So(err, ShouldBeNil)
+			So(changeType, ShouldEqual, ChangeLanguages)
+		})
+
+		Convey("Then an unknown name should return an error", func() {
+			var changeType ChangeType
+			So(changeType.UnmarshalText([]byte("unknown")), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,639 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"golang.org/x/net/context"
+	"golang.org/x/text/language"
+)
+
+// mutation is a write to storage along with how to undo it
+type mutation struct {
+	op        string
+	languages bool
+
+	// fallbacks marks a write to fallback settings, storages that do not
+	// implement FallbackStorage are skipped without an error
+	fallbacks bool
+
+	// apply makes the write to a storage
+	apply func(context.Context, Storage) error
+
+	// prepare reads the state of a storage before apply, returning a function
+	// that restores it
+	prepare func(context.Context, Storage) (func() error, error)
+
+	// partial reports whether a storage may be left part way through by a
+	// failed apply, its state is then read first even if no other storage is
+	// written
+	partial func(Storage) bool
+}
+
+// RollbackError is returned when a write fails on a storage after other
+// storages accepted it. The write is undone on those storages and on the one
+// that failed, as it may have been applied in part. Storages that could not be
+// restored are listed in RollbackErrors.
+type RollbackError struct {
+	Op      string
+	Backend string
+	Err     error
+
+	RolledBack     []string
+	RollbackErrors []error
+}
+
+func (err *RollbackError) Error() string {
+	msg := fmt.Sprintf("i18n: %s failed on %s: %s", err.Op, err.Backend, err.Err)
+
+	if len(err.RolledBack) > 0 {
+		msg += "; rolled back " + strings.Join(err.RolledBack, ", ")
+	}
+
+	if len(err.RollbackErrors) > 0 {
+		reasons := make([]string, 0, len(err.RollbackErrors))
+		for _, e := range err.RollbackErrors {
+			reasons = append(reasons, e.Error())
+		}
+		msg += "; rollback failed: " + strings.Join(reasons, "; ")
+	}
+
+	return msg
+}
+
+// Unwrap returns the error from the storage that failed
+func (err *RollbackError) Unwrap() error {
+	return err.Err
+}
+
+// backend names a storage for error reports
+func backend(i int, s Storage) string {
+	return fmt.Sprintf("storage %d (%T)", i, s)
+}
+
+// write applies a mutation to every storage that supports it, all or nothing.
+// Storages without the capability, or that return ErrReadOnly, are skipped
+// and a WriteError is returned if no storage accepts the write. Storages that
+// do not support the write at all, returning ErrUnsupported, are skipped and
+// ErrUnsupported is returned if every storage written to returns it.
+//
+// When more than one storage is written, or the write may be applied in part,
+// the state each one needs restoring to is read before anything is written.
+// If a storage then fails, it and the storages that accepted the write are
+// restored and a RollbackError is returned. The restore is made even if the
+// context is done.
+func (i18n *I18n) write(ctx context.Context, m mutation) error {
+	var errs []error
+	var targets []int
+
+	for i, s := range i18n.storage {
+		if _, ok := WithFallbackContext(s); m.fallbacks && !ok {
+			continue
+		}
+
+		capabilities := CapabilitiesOf(s)
+		if (m.languages && !capabilities.Languages) || (!m.languages && !capabilities.Writable) {
+			errs = append(errs, &StorageError{
+				Backend: backend(i, s),
+				Op:      m.op,
+				Err:     ErrReadOnly,
+			})
+			continue
+		}
+		targets = append(targets, i)
+	}
+
+	undo := make(map[int]func() error, len(targets))
+
+	for _, i := range targets {
+		if len(targets) == 1 && (m.partial == nil || !m.partial(i18n.storage[i])) {
+			continue
+		}
+
+		restore, err := m.prepare(ctx, i18n.storage[i])
+		if err != nil {
+			return err
+		}
+		undo[i] = restore
+	}
+
+	var accepted []int
+
+	for _, i := range targets {
+		err := m.apply(ctx, i18n.storage[i])
+		if errors.Is(err, ErrUnsupported) {
+			continue
+		}
+		if errors.Is(err, ErrReadOnly) {
+			errs = append(errs, err)
+			continue
+		}
+
+		if err != nil {
+			return i18n.rollback(m.op, i, err, append(accepted, i), undo)
+		}
+
+		accepted = append(accepted, i)
+	}
+
+	if len(accepted) == 0 && len(errs) > 0 {
+		return &WriteError{
+			Op:     m.op,
+			Errors: errs,
+		}
+	}
+
+	if len(accepted) == 0 && len(targets) > 0 {
+		return ErrUnsupported
+	}
+
+	return nil
+}
+
+// rollback restores the storages written to, most recent first. Storages
+// are only restored if their state was read before the write.
+func (i18n *I18n) rollback(op string, failed int, err error, written []int, undo map[int]func() error) error {
+	rollbackErr := &RollbackError{
+		Op:      op,
+		Backend: backend(failed, i18n.storage[failed]),
+		Err:     err,
+	}
+
+	for j := len(written) - 1; j >= 0; j-- {
+		i := written[j]
+		if undo[i] == nil {
+			continue
+		}
+		name := backend(i, i18n.storage[i])
+
+		if err := undo[i](); err != nil {
+			rollbackErr.RollbackErrors = append(rollbackErr.RollbackErrors, &StorageError{
+				Backend: name,
+				Op:      "rollback " + op,
+				Err:     err,
+			})
+			continue
+		}
+
+		rollbackErr.RolledBack = append(rollbackErr.RolledBack, name)
+	}
+
+	return rollbackErr
+}
+
+// commit publishes translations written to storage to the catalog and
+// notifies listeners. It must be called with the lock held.
+func (i18n *I18n) commit(stored []*Translation, messages []*Message, deleted []*Translation) {
+	stored, messages, deleted = i18n.resolve(stored, messages, deleted)
+
+	next := i18n.load()
+	if len(stored) > 0 {
+		next = next.withTranslations(stored, messages)
+	}
+	if len(deleted) > 0 {
+		next = next.withoutTranslations(deleted)
+	}
+
+	i18n.catalog.Store(next)
+	i18n.publish(ChangesFor(ChangeUpsert, stored)...)
+	i18n.publish(ChangesFor(ChangeDelete, deleted)...)
+}
+
+// resolve gets the translations to publish for a write. Keys written to a
+// LayeredStorage are resolved through its layers, so a higher layer still
+// shadows a store and a delete reveals the translation in another layer. The
+// last LayeredStorage decides, as the last storage does in a sync.
+func (i18n *I18n) resolve(stored []*Translation, messages []*Message, deleted []*Translation) ([]*Translation, []*Message, []*Translation) {
+	layered := i18n.layered()
+	if layered == nil {
+		return stored, messages, deleted
+	}
+
+	servedStored, err := layered.resolve(stored, false)
+	if err != nil {
+		i18n.logger().Warn("resolving written translations failed", "error", err)
+		return stored, messages, deleted
+	}
+
+	servedDeleted, err := layered.resolve(deleted, true)
+	if err != nil {
+		i18n.logger().Warn("resolving deleted translations failed", "error", err)
+		return stored, messages, deleted
+	}
+
+	var nextStored []*Translation
+	var nextMessages []*Message
+	var nextDeleted []*Translation
+
+	add := func(translation *Translation) {
+		message, err := translation.message()
+		if err != nil {
+			i18n.logger().Warn("skipping translation that can not be parsed", "lang", translation.Lang.String(), "key", translation.Key, "error", err)
+			return
+		}
+		nextStored = append(nextStored, translation)
+		nextMessages = append(nextMessages, message)
+	}
+
+	for i, translation := range servedStored {
+		switch translation {
+		case nil:
+			nextDeleted = append(nextDeleted, stored[i])
+		case stored[i]:
+			nextStored = append(nextStored, translation)
+			nextMessages = append(nextMessages, messages[i])
+		default:
+			add(translation)
+		}
+	}
+
+	for i, translation := range servedDeleted {
+		if translation == nil {
+			nextDeleted = append(nextDeleted, deleted[i])
+			continue
+		}
+		add(translation)
+	}
+
+	return nextStored, nextMessages, nextDeleted
+}
+
+// layered gets the last LayeredStorage, which decides what is served for the
+// keys it has
+func (i18n *I18n) layered() *LayeredStorage {
+	var layered *LayeredStorage
+	for _, s := range i18n.storage {
+		if l, ok := unwrap(s).(*LayeredStorage); ok {
+			layered = l
+		}
+	}
+	return layered
+}
+
+// existing gets copies of the stored versions of translations, copies are
+// needed as storages may update stored translations in place. Only the keys
+// of the translations are read from storages implementing KeyStorage, and only
+// their languages from storages implementing LanguageStorage.
+func existing(ctx context.Context, s Storage, translations []*Translation) (map[string]*Translation, error) {
+	wanted := make(map[string]bool, len(translations))
+	var tags []language.Tag
+
+	for _, translation := range translations {
+		wanted[batchKey(translation)] = true
+		tags = appendTag(tags, translation.Lang)
+	}
+
+	var stored []*Translation
+
+	switch unwrap(s).(type) {
+	case KeyStorage:
+		for _, translation := range translations {
+			result, err := GetKeyContext(ctx, s, translation.Lang, translation.Key)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			stored = append(stored, result)
+		}
+	case LanguageStorage:
+		for _, tag := range tags {
+			results, err := GetLanguageContext(ctx, s, tag)
+			if err != nil {
+				return nil, err
+			}
+			stored = append(stored, results...)
+		}
+	default:
+		all, err := WithContext(s).GetAllContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		stored = all
+	}
+
+	found := make(map[string]*Translation)
+	for _, translation := range stored {
+		if k := batchKey(translation); wanted[k] {
+			copied := *translation
+			found[k] = &copied
+		}
+	}
+
+	return found, nil
+}
+
+// appendTag adds a language to a list if it is not already in it
+func appendTag(tags []language.Tag, tag language.Tag) []language.Tag {
+	for _, t := range tags {
+		if t.String() == tag.String() {
+			return tags
+		}
+	}
+	return append(tags, tag)
+}
+
+// restoreTranslations undoes a write to translations, translations that
+// existed are stored again and the rest deleted. Only translations that
+// differ from what was read are written, so a storage the write never reached
+// is left alone.
+func restoreTranslations(ctx context.Context, s Storage, translations []*Translation) (func() error, error) {
+	found, err := existing(ctx, s, translations)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		current, err := existing(context.Background(), s, translations)
+		if err != nil {
+			return err
+		}
+
+		var restore, remove []*Translation
+		seen := make(map[string]bool, len(translations))
+
+		for _, translation := range translations {
+			k := batchKey(translation)
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+
+			previous, existed := found[k]
+			now, exists := current[k]
+
+			switch {
+			case existed && !reflect.DeepEqual(previous, now):
+				restore = append(restore, previous)
+			case !existed && exists:
+				remove = append(remove, translation)
+			}
+		}
+
+		if err := DeleteMany(s, remove); err != nil {
+			return err
+		}
+
+		return StoreMany(s, restore)
+	}, nil
+}
+
+func storeTranslations(op string, translations []*Translation) mutation {
+	return mutation{
+		op: op,
+		apply: func(ctx context.Context, s Storage) error {
+			if len(translations) == 1 {
+				return WithContext(s).StoreContext(ctx, translations[0])
+			}
+			return StoreManyContext(ctx, s, translations)
+		},
+		prepare: func(ctx context.Context, s Storage) (func() error, error) {
+			return restoreTranslations(ctx, s, translations)
+		},
+	}
+}
+
+func deleteTranslations(op string, translations []*Translation) mutation {
+	return mutation{
+		op: op,
+		apply: func(ctx context.Context, s Storage) error {
+			if len(translations) == 1 {
+				return WithContext(s).DeleteContext(ctx, translations[0])
+			}
+			return DeleteManyContext(ctx, s, translations)
+		},
+		prepare: func(ctx context.Context, s Storage) (func() error, error) {
+			return restoreTranslations(ctx, s, translations)
+		},
+	}
+}
+
+// moveTranslations stores one set of translations and deletes another as a
+// single write. Storages that are not a MoveStorage may fail part way through,
+// so they are restored before the error is returned.
+func moveTranslations(op string, store []*Translation, remove []*Translation) mutation {
+	all := append(append([]*Translation(nil), store...), remove...)
+
+	return mutation{
+		op: op,
+		apply: func(ctx context.Context, s Storage) error {
+			return MoveContext(ctx, s, store, remove)
+		},
+		prepare: func(ctx context.Context, s Storage) (func() error, error) {
+			return restoreTranslations(ctx, s, all)
+		},
+		partial: func(s Storage) bool {
+			return !movesAtomically(s)
+		},
+	}
+}
+
+// movesAtomically reports whether Move on a storage is a single operation, a
+// LayeredStorage moves as its writable layer does
+func movesAtomically(s Storage) bool {
+	s = unwrap(s)
+	if layered, ok := s.(*LayeredStorage); ok {
+		return movesAtomically(layered.target())
+	}
+
+	_, ok := s.(MoveStorage)
+	return ok
+}
+
+// restoreSupportedLanguages undoes a write to the supported languages,
+// languages added since are removed and languages removed are added again
+func restoreSupportedLanguages(ctx context.Context, s Storage) (func() error, error) {
+	previous, err := WithContext(s).SupportedLanguagesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		current, err := s.SupportedLanguages()
+		if err != nil {
+			return err
+		}
+
+		for _, tag := range current {
+			if !containsTag(previous, tag) {
+				if err := s.DeleteSupportedLanguage(tag); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, tag := range previous {
+			if !containsTag(current, tag) {
+				if err := s.StoreSupportedLanguage(tag); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}, nil
+}
+
+// containsTag reports whether a language is in a list
+func containsTag(tags []language.Tag, tag language.Tag) bool {
+	for _, t := range tags {
+		if t.String() == tag.String() {
+			return true
+		}
+	}
+	return false
+}
+
+func storeSupportedLanguages(tags []language.Tag) mutation {
+	return mutation{
+		op:        "AddSupportedLanguage",
+		languages: true,
+		apply: func(ctx context.Context, s Storage) error {
+			for _, tag := range tags {
+				if err := WithContext(s).StoreSupportedLanguageContext(ctx, tag); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		prepare: restoreSupportedLanguages,
+	}
+}
+
+func deleteSupportedLanguage(tag language.Tag) mutation {
+	return mutation{
+		op:        "RemoveSupportedLanguage",
+		languages: true,
+		apply: func(ctx context.Context, s Storage) error {
+			return WithContext(s).DeleteSupportedLanguageContext(ctx, tag)
+		},
+		prepare: restoreSupportedLanguages,
+	}
+}
+
+// setDefaultLanguage also adds the language to the supported languages, the
+// restore undoes both
+func setDefaultLanguage(tag language.Tag) mutation {
+	return mutation{
+		op:        "SetDefaultLanguage",
+		languages: true,
+		apply: func(ctx context.Context, s Storage) error {
+			if err := WithContext(s).StoreSupportedLanguageContext(ctx, tag); err != nil {
+				return err
+			}
+			return WithContext(s).SetDefaultLanguageContext(ctx, tag)
+		},
+		prepare: func(ctx context.Context, s Storage) (func() error, error) {
+			previous, err := WithContext(s).DefaultLanguageContext(ctx)
+			if errors.Is(err, ErrNotFound) {
+				previous, err = language.Und, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			restore, err := restoreSupportedLanguages(ctx, s)
+			if err != nil {
+				return nil, err
+			}
+
+			return func() error {
+				current, err := s.DefaultLanguage()
+				if err != nil && !errors.Is(err, ErrNotFound) {
+					return err
+				}
+
+				// Storages may add the default language to the supported
+				// languages, so those are restored after it
+				if err != nil || current.String() != previous.String() {
+					if err := s.SetDefaultLanguage(previous); err != nil {
+						return err
+					}
+				}
+
+				return restore()
+			}, nil
+		},
+	}
+}
+
+// restoreFallback undoes a write to the fallback chain of a language
+func restoreFallback(ctx context.Context, s Storage, tag language.Tag) (func() error, error) {
+	fs, _ := WithFallbackContext(s)
+
+	fallbacks, err := fs.FallbacksContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous *Fallback
+	for _, f := range fallbacks {
+		if f.Lang.String() == tag.String() {
+			previous = f.copy()
+		}
+	}
+
+	return func() error {
+		if previous == nil {
+			return fs.DeleteFallback(tag)
+		}
+		return fs.StoreFallback(previous)
+	}, nil
+}
+
+func storeFallback(fallback *Fallback) mutation {
+	return mutation{
+		op:        "SetFallback",
+		languages: true,
+		fallbacks: true,
+		apply: func(ctx context.Context, s Storage) error {
+			fs, _ := WithFallbackContext(s)
+			return fs.StoreFallbackContext(ctx, fallback)
+		},
+		prepare: func(ctx context.Context, s Storage) (func() error, error) {
+			return restoreFallback(ctx, s, fallback.Lang)
+		},
+	}
+}
+
+func deleteFallback(tag language.Tag) mutation {
+	return mutation{
+		op:        "RemoveFallback",
+		languages: true,
+		fallbacks: true,
+		apply: func(ctx context.Context, s Storage) error {
+			fs, _ := WithFallbackContext(s)
+			return fs.DeleteFallbackContext(ctx, tag)
+		},
+		prepare: func(ctx context.Context, s Storage) (func() error, error) {
+			return restoreFallback(ctx, s, tag)
+		},
+	}
+}
+
+func setFallbackToDefault(enabled bool) mutation {
+	return mutation{
+		op:        "SetFallbackToDefault",
+		languages: true,
+		fallbacks: true,
+		apply: func(ctx context.Context, s Storage) error {
+			fs, _ := WithFallbackContext(s)
+			return fs.SetFallbackToDefaultContext(ctx, enabled)
+		},
+		prepare: func(ctx context.Context, s Storage) (func() error, error) {
+			fs, _ := WithFallbackContext(s)
+
+			previous, err := fs.FallbackToDefaultContext(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			return func() error {
+				return fs.SetFallbackToDefault(previous)
+			}, nil
+		},
+	}
+}
@@ -0,0 +1,305 @@
+package i18n
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/text/language"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var errFailing = errors.New("failing storage")
+
+// failingStorage fails every translation and fallback write
+type failingStorage struct {
+	FallbackStorage
+}
+
+func (storage *failingStorage) Store(*Translation) error {
+	return errFailing
+}
+
+func (storage *failingStorage) StoreMany([]*Translation) error {
+	return errFailing
+}
+
+func (storage *failingStorage) Delete(*Translation) error {
+	return errFailing
+}
+
+func (storage *failingStorage) StoreFallback(*Fallback) error {
+	return errFailing
+}
+
+// partialStorage fails to store one key, so writing many translations fails
+// part way through
+type partialStorage struct {
+	FallbackStorage
+	fail string
+}
+
+func (storage *partialStorage) Store(translation *Translation) error {
+	if translation.Key == storage.fail {
+		return errFailing
+	}
+	return storage.FallbackStorage.Store(translation)
+}
+
+// languageFailingStorage fails writes of one language to the language settings
+type languageFailingStorage struct {
+	FallbackStorage
+	fail language.Tag
+}
+
+func (storage *languageFailingStorage) StoreSupportedLanguage(tag language.Tag) error {
+	if tag.String() == storage.fail.String() {
+		return errFailing
+	}
+	return storage.FallbackStorage.StoreSupportedLanguage(tag)
+}
+
+func (storage *languageFailingStorage) SetDefaultLanguage(tag language.Tag) error {
+	if tag.String() == storage.fail.String() {
+		return errFailing
+	}
+	return storage.FallbackStorage.SetDefaultLanguage(tag)
+}
+
+// unsupportedStorage does not support any translation write
+type unsupportedStorage struct {
+	Storage
+}
+
+func (storage *unsupportedStorage) Store(*Translation) error {
+	return ErrUnsupported
+}
+
+// keyStorage counts full reads, translations can be read one key at a time
+type keyStorage struct {
+	Storage
+	reads int
+}
+
+func (storage *keyStorage) GetAll() ([]*Translation, error) {
+	storage.reads++
+	return storage.Storage.GetAll()
+}
+
+func (storage *keyStorage) GetKey(tag language.Tag, key string) (*Translation, error) {
+	return storage.Storage.(KeyStorage).GetKey(tag, key)
+}
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a storage that fails after another storage accepts writes", t, func() {
+		first := NewInMemoryStorage().(FallbackStorage)
+		i18n := New(first, &failingStorage{NewInMemoryStorage().(FallbackStorage)})
+
+		existingValue := &Translation{
+			Lang:  language.English,
+			Key:   "SomeKey",
+			Value: "SomeValue",
+		}
+		first.Store(existingValue)
+		first.StoreFallback(&Fallback{Lang: language.CanadianFrench, Chain: []language.Tag{language.English}})
+		i18n.Sync()
+
+		Convey("When an existing translation is updated", func() {
+			err := i18n.Add(&Translation{
+				Lang:  language.English,
+				Key:   "SomeKey",
+				Value: "SomeOtherValue",
+			})
+
+			Convey("Then the failure and rollback should be reported", func() {
+				var rollbackErr *RollbackError
+				So(errors.As(err, &rollbackErr), ShouldBeTrue)
+				So(errors.Is(err, errFailing), ShouldBeTrue)
+				So(rollbackErr.Op, ShouldEqual, "Add")
+				So(rollbackErr.Backend, ShouldContainSubstring, "storage 1")
+				So(rollbackErr.RolledBack, ShouldHaveLength, 2)
+				So(rollbackErr.RolledBack[0], ShouldContainSubstring, "storage 1")
+				So(rollbackErr.RolledBack[1], ShouldContainSubstring, "storage 0")
+				So(rollbackErr.RollbackErrors, ShouldBeEmpty)
+			})
+
+			Convey("Then the first storage should have the previous value", func() {
+				results, err := first.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Value, ShouldEqual, "SomeValue")
+			})
+
+			Convey("Then the catalog should be unchanged", func() {
+				So(i18n.T(language.English, "SomeKey"), ShouldEqual, "SomeValue")
+			})
+		})
+
+		Convey("When new translations are added", func() {
+			err := i18n.AddMany([]*Translation{
+				{Lang: language.English, Key: "OtherKey", Value: "OtherValue"},
+				{Lang: language.Spanish, Key: "SomeKey", Value: "SomeSpanishValue"},
+			})
+
+			Convey("Then they should be removed from the first storage", func() {
+				So(err, ShouldHaveSameTypeAs, &RollbackError{})
+
+				results, err := first.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Key, ShouldEqual, "SomeKey")
+			})
+		})
+
+		Convey("When a translation is deleted", func() {
+			err := i18n.Delete(&Translation{
+				Lang: language.English,
+				Key:  "SomeKey",
+			})
+
+			Convey("Then it should be restored in the first storage", func() {
+				So(err, ShouldHaveSameTypeAs, &RollbackError{})
+
+				results, err := first.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Value, ShouldEqual, "SomeValue")
+				So(i18n.T(language.English, "SomeKey"), ShouldEqual, "SomeValue")
+			})
+		})
+
+		Convey("When a fallback chain is replaced", func() {
+			err := i18n.SetFallback(language.CanadianFrench, language.French)
+
+			Convey("Then the previous chain should be restored in the first storage", func() {
+				So(err, ShouldHaveSameTypeAs, &RollbackError{})
+
+				fallbacks, err := first.Fallbacks()
+				So(err, ShouldBeNil)
+				So(fallbacks, ShouldHaveLength, 1)
+				So(fallbacks[0].Chain, ShouldResemble, []language.Tag{language.English})
+				So(i18n.GetFallback(language.CanadianFrench), ShouldResemble, []language.Tag{language.English})
+			})
+		})
+
+		Reset(func() {
+			i18n.Close()
+		})
+	})
+
+	Convey("Given a storage that fails part way through a write", t, func() {
+		first := NewInMemoryStorage().(FallbackStorage)
+		partial := &partialStorage{NewInMemoryStorage().(FallbackStorage), "OtherKey"}
+		i18n := New(first, partial)
+
+		Convey("When translations are added", func() {
+			err := i18n.AddMany([]*Translation{
+				{Lang: language.English, Key: "SomeKey", Value: "SomeValue"},
+				{Lang: language.English, Key: "OtherKey", Value: "OtherValue"},
+			})
+
+			Convey("Then the failing storage should be restored too", func() {
+				So(err, ShouldHaveSameTypeAs, &RollbackError{})
+
+				results, err := partial.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given a single storage that fails part way through a move", t, func() {
+		partial := &partialStorage{NewInMemoryStorage().(FallbackStorage), "cart.checkout.title"}
+		partial.Store(&Translation{Lang: language.English, Key: "checkout.button", Value: "Pay"})
+		partial.Store(&Translation{Lang: language.English, Key: "checkout.title", Value: "Checkout"})
+
+		i18n := New(partial)
+		i18n.Sync()
+
+		Convey("When a group is moved", func() {
+			err := i18n.Group("checkout").MoveTo("cart.checkout")
+
+			Convey("Then the storage should be restored", func() {
+				So(err, ShouldHaveSameTypeAs, &RollbackError{})
+
+				results, err := partial.GetAll()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 2)
+				for _, result := range results {
+					So(result.Key, ShouldStartWith, "checkout.")
+				}
+			})
+		})
+	})
+
+	Convey("Given a storage that fails to store a language", t, func() {
+		first := NewInMemoryStorage().(FallbackStorage)
+		second := &languageFailingStorage{NewInMemoryStorage().(FallbackStorage), language.German}
+		i18n := New(first, second)
+
+		Convey("When several supported languages are added", func() {
+			err := i18n.AddSupportedLanguage(language.French, language.German)
+
+			Convey("Then none should be added", func() {
+				So(err, ShouldHaveSameTypeAs, &RollbackError{})
+				So(i18n.GetSupportedLanguages(), ShouldBeEmpty)
+
+				langs, err := second.SupportedLanguages()
+				So(err, ShouldBeNil)
+				So(langs, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When the default language is set", func() {
+			So(i18n.SetDefaultLanguage(language.English), ShouldBeNil)
+
+			err := i18n.SetDefaultLanguage(language.German)
+
+			Convey("Then the language should not be left supported", func() {
+				So(err, ShouldHaveSameTypeAs, &RollbackError{})
+
+				langs, err := first.SupportedLanguages()
+				So(err, ShouldBeNil)
+				So(langs, ShouldResemble, []language.Tag{language.English})
+
+				lang, err := first.DefaultLanguage()
+				So(err, ShouldBeNil)
+				So(lang, ShouldResemble, language.English)
+
+				So(i18n.GetSupportedLanguages(), ShouldResemble, []language.Tag{language.English})
+				So(i18n.GetDefaultLanguage(), ShouldResemble, language.English)
+			})
+		})
+	})
+	Convey("Given storages that do not support translation writes", t, func() {
+		i18n := New(&unsupportedStorage{NewInMemoryStorage()}, &unsupportedStorage{NewInMemoryStorage()})
+
+		Convey("When a translation is added", func() {
+			err := i18n.Add(&Translation{Lang: language.English, Key: "SomeKey", Value: "SomeValue"})
+
+			Convey("Then ErrUnsupported should be returned and nothing published", func() {
+				So(err, ShouldEqual, ErrUnsupported)
+				So(i18n.Get(language.English, "SomeKey"), ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given two storages that can read single keys", t, func() {
+		first := &keyStorage{Storage: NewInMemoryStorage()}
+		second := &keyStorage{Storage: NewInMemoryStorage()}
+		i18n := New(first, second)
+
+		Convey("When a translation is added", func() {
+			err := i18n.Add(&Translation{Lang: language.English, Key: "SomeKey", Value: "SomeValue"})
+
+			Convey("Then only its key should be read to prepare the rollback", func() {
+				So(err, ShouldBeNil)
+				So(first.reads, ShouldEqual, 0)
+				So(second.reads, ShouldEqual, 0)
+				So(i18n.T(language.English, "SomeKey"), ShouldEqual, "SomeValue")
+			})
+		})
+	})
+}